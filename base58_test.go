@@ -0,0 +1,17 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBase58RoundTrip(t *testing.T) {
+	for _, data := range [][]byte{{0x05, 0x01, 0x02}, {0x00, 0x00, 0xff}, {0x00, 0x10}} {
+		assert.Equal(t, data, Base58Decode(Base58Encode(data)))
+	}
+
+	address := Base58Encode(append([]byte{scriptVersion}, bytes.Repeat([]byte{0xab}, 24)...))
+	assert.Equal(t, byte('3'), address[0], "Script addresses start with 3")
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/gob"
+	"fmt"
 	"log"
 	"time"
 )
@@ -13,10 +14,14 @@ type Block struct {
 	PrevBlockHash []byte
 	Hash          []byte
 	Nonce         int
+	Height        int
+	// Hash of the transaction witnesses, which the transaction IDs don't cover
+	WitnessCommitment []byte
 }
 
-func NewBlock(transaction []*Transaction, prevBlockHash []byte) *Block {
-	block := &Block{time.Now().Unix(), transaction, prevBlockHash, []byte{}, 0}
+func NewBlock(transaction []*Transaction, prevBlockHash []byte, height int) *Block {
+	block := &Block{time.Now().Unix(), transaction, prevBlockHash, []byte{}, 0, height, nil}
+	block.WitnessCommitment = block.HashWitnesses()
 	pow := NewProofOfWork(block)
 	nonce, hash := pow.Run()
 
@@ -27,7 +32,7 @@ func NewBlock(transaction []*Transaction, prevBlockHash []byte) *Block {
 }
 
 func NewGenesisBlock(coinbase *Transaction) *Block {
-	return NewBlock([]*Transaction{coinbase}, []byte{})
+	return NewBlock([]*Transaction{coinbase}, []byte{}, 0)
 }
 
 func (b *Block) Serialize() []byte {
@@ -53,15 +58,48 @@ func DeserializeBlock(d []byte) *Block {
 	return block
 }
 
-// HashTransactions returns a hash of the transactions in the block
+// HashTransactions returns a hash of the IDs of the transactions in the block
 func (b *Block) HashTransactions() []byte {
 	transactions := [][]byte{}
 
 	for _, tx := range b.Transactions {
-		transactions = append(transactions, tx.Serialize())
+		transactions = append(transactions, tx.ID)
 	}
 
 	tree := NewMerkleTree(transactions)
 
 	return tree.RootNode.Data
 }
+
+// HashWitnesses returns a hash of the transactions in the block with
+// their witnesses
+func (b *Block) HashWitnesses() []byte {
+	transactions := [][]byte{}
+
+	for _, tx := range b.Transactions {
+		transactions = append(transactions, tx.WitnessHash())
+	}
+
+	tree := NewMerkleTree(transactions)
+
+	return tree.RootNode.Data
+}
+
+// HasValidWitnessCommitment checks that the witnesses in the block are
+// the ones it was mined with
+func (b *Block) HasValidWitnessCommitment() bool {
+	return bytes.Equal(b.WitnessCommitment, b.HashWitnesses())
+}
+
+// Validate checks the proof of work of the block and that its witness
+// commitment matches the witnesses of its transactions
+func (b *Block) Validate() error {
+	if !NewProofOfWork(b).Validate() {
+		return fmt.Errorf("Block %x has an invalid proof of work", b.Hash)
+	}
+	if !b.HasValidWitnessCommitment() {
+		return fmt.Errorf("Block %x doesn't commit to the witnesses of its transactions", b.Hash)
+	}
+
+	return nil
+}
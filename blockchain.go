@@ -2,12 +2,12 @@ package main
 
 import (
 	"bytes"
-	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/boltdb/bolt"
 )
@@ -17,23 +17,37 @@ const blocksBucket = "blocks"
 const genesisCoinbaseData = "The Times 03/Jan/2009 Chancellor on brink of second bailout for banks"
 
 type Blockchain struct {
-	tip []byte
-	db  *bolt.DB
+	tip      []byte
+	db       *bolt.DB
+	config   ChainConfig
+	verifier ScriptVerifier
 }
 
-// MineBlock mines a new block with the provided transactions
+// MineBlock mines a new block with the provided transactions. The
+// coinbase transaction, which comes first, is paid the fees of the others
 func (bc *Blockchain) MineBlock(transactions []*Transaction) *Block {
+	err := bc.VerifyTransactions(transactions)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fees := 0
 	for _, tx := range transactions {
-		if bc.VerifyTransaction(tx) != true {
-			log.Panic("Error: Invalid transaction")
-		}
+		fees += bc.TransactionFee(tx)
+	}
+	if fees > 0 && len(transactions) > 0 && transactions[0].IsCoinbase() {
+		transactions[0].Vout[0].Value += fees
+		transactions[0].ID = transactions[0].Hash()
 	}
 
 	lastHash := []byte{}
+	lastHeight := 0
 
-	err := bc.db.View(func(tx *bolt.Tx) error {
+	err = bc.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
 		lastHash = b.Get([]byte("l"))
+		lastBlock := DeserializeBlock(b.Get(lastHash))
+		lastHeight = lastBlock.Height
 		return nil
 	})
 
@@ -41,7 +55,11 @@ func (bc *Blockchain) MineBlock(transactions []*Transaction) *Block {
 		log.Panic(err)
 	}
 
-	newBlock := NewBlock(transactions, lastHash)
+	newBlock := NewBlock(transactions, lastHash, lastHeight+1)
+	err = newBlock.Validate()
+	if err != nil {
+		log.Panic(err)
+	}
 
 	err = bc.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
@@ -59,9 +77,31 @@ func (bc *Blockchain) MineBlock(transactions []*Transaction) *Block {
 		return nil
 	})
 
+	if err != nil {
+		log.Panic(err)
+	}
+
 	return newBlock
 }
 
+// GetBestHeight returns the height of the latest block
+func (bc *Blockchain) GetBestHeight() int {
+	lastBlock := &Block{}
+
+	err := bc.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(blocksBucket))
+		lastHash := b.Get([]byte("l"))
+		lastBlock = DeserializeBlock(b.Get(lastHash))
+		return nil
+	})
+
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return lastBlock.Height
+}
+
 // FindUnspentTransactions returns a list of transactions containing unspent outputs
 func (bc *Blockchain) FindUnspentTransactions(pubKeyHash []byte) []Transaction {
 	unspentTXs := []Transaction{}
@@ -74,8 +114,8 @@ func (bc *Blockchain) FindUnspentTransactions(pubKeyHash []byte) []Transaction {
 			txID := hex.EncodeToString(tx.ID)
 
 			if tx.IsCoinbase() == false {
-				for _, input := range tx.Vin {
-					if input.UsesKey(pubKeyHash) {
+				for index, input := range tx.Vin {
+					if tx.InputUsesKey(index, pubKeyHash) {
 						inTxID := hex.EncodeToString(input.Txid)
 						// Append index of output referenced by input
 						spentTXs[inTxID] = append(spentTXs[inTxID], input.Vout)
@@ -148,6 +188,10 @@ func (bc *Blockchain) FindUTXO() map[string]TXOutputs {
 
 		Outputs:
 			for outputIndex, output := range tx.Vout {
+				if output.IsUnspendable() {
+					continue
+				}
+
 				// Was the output spent?
 				if spentTXOs[txID] != nil {
 					for _, spentOutIndex := range spentTXOs[txID] {
@@ -157,9 +201,12 @@ func (bc *Blockchain) FindUTXO() map[string]TXOutputs {
 					}
 				}
 
-				outs := UTXO[txID]
-				outs.Outputs = append(outs.Outputs, output)
-				UTXO[txID] = outs
+				outs, ok := UTXO[txID]
+				if !ok {
+					outs = NewTXOutputs(tx, block.Height)
+					UTXO[txID] = outs
+				}
+				outs.Outputs[outputIndex] = output
 			}
 
 			if tx.IsCoinbase() == false {
@@ -169,33 +216,206 @@ func (bc *Blockchain) FindUTXO() map[string]TXOutputs {
 				}
 			}
 		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
 	}
 
 	return UTXO
 }
 
+// HistoryEntry is what a transaction moved in and out of some addresses
+type HistoryEntry struct {
+	Height    int
+	TxID      []byte
+	Received  int
+	Sent      int
+	Addresses map[string]bool
+}
+
+// FindHistory returns the transactions paying to or spending from any of
+// the addresses, oldest first
+func (bc *Blockchain) FindHistory(addresses []string) []HistoryEntry {
+	scripts := addressScripts(addresses)
+	blocks := []*Block{}
+	bci := bc.Iterator()
+
+	for {
+		block := bci.Next()
+		blocks = append([]*Block{block}, blocks...)
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	// Outputs paying to the addresses, by outpoint, until they are spent
+	owned := make(map[string]TXOutput)
+	history := []HistoryEntry{}
+
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			entry := HistoryEntry{block.Height, tx.ID, 0, 0, make(map[string]bool)}
+
+			if tx.IsCoinbase() == false {
+				for _, input := range tx.Vin {
+					outpoint := fmt.Sprintf("%x:%d", input.Txid, input.Vout)
+					if output, ok := owned[outpoint]; ok {
+						entry.Sent += output.Value
+						entry.Addresses[scripts[string(output.ScriptPubKey)]] = true
+						delete(owned, outpoint)
+					}
+				}
+			}
+
+			for outputIndex, output := range tx.Vout {
+				if address, ok := scripts[string(output.ScriptPubKey)]; ok {
+					entry.Received += output.Value
+					entry.Addresses[address] = true
+					owned[fmt.Sprintf("%x:%d", tx.ID, outputIndex)] = output
+				}
+			}
+
+			if len(entry.Addresses) > 0 {
+				history = append(history, entry)
+			}
+		}
+	}
+
+	return history
+}
+
+// FindUsedPubKeyHashes returns the hex pubkey hashes that outputs of the
+// chain have been locked with, spent or not
+func (bc *Blockchain) FindUsedPubKeyHashes() map[string]bool {
+	used := make(map[string]bool)
+	bci := bc.Iterator()
+
+	for {
+		block := bci.Next()
+
+		for _, tx := range block.Transactions {
+			for _, output := range tx.Vout {
+				if pubKeyHash := output.ScriptPubKey.PubKeyHash(); pubKeyHash != nil {
+					used[hex.EncodeToString(pubKeyHash)] = true
+				}
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return used
+}
+
+// TransactionFee returns how much the outputs spent by a Transaction are
+// worth beyond its own outputs, which is left to the miner
+func (bc *Blockchain) TransactionFee(tx *Transaction) int {
+	if tx.IsCoinbase() {
+		return 0
+	}
+
+	return tx.Fee(bc.findPrevTransactions(tx))
+}
+
 // SignTransaction signs inputs of a Transaction
-func (bc *Blockchain) SignTransaction(tx *Transaction, privKey ecdsa.PrivateKey) {
-	prevTXs := make(map[string]Transaction)
+func (bc *Blockchain) SignTransaction(tx *Transaction, signer Signer) {
+	prevTXs := bc.findPrevTransactions(tx)
 
-	for _, vin := range tx.Vin {
-		// Find old transactions that the inputs of this new transaction reference
-		prevTX, err := bc.FindTransaction(vin.Txid)
+	tx.Sign(signer, prevTXs, SigHashAll)
+}
+
+// SignTransactionWithWallets signs every input of a Transaction with the key
+// of the wallet owning the referenced output
+func (bc *Blockchain) SignTransactionWithWallets(tx *Transaction, wallets *Wallets) {
+	if tx.IsCoinbase() {
+		return
+	}
+
+	prevTXs := bc.findPrevTransactions(tx)
+	hashes := NewSigHashes(tx)
+
+	for index, vin := range tx.Vin {
+		prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
+		wallet := wallets.GetWalletByPubKeyHash(prevTx.Vout[vin.Vout].ScriptPubKey.PubKeyHash())
+		if wallet == nil {
+			log.Panicf("ERROR: No key in the wallet for input %d", index)
+		}
+
+		signer, err := wallet.Signer()
 		if err != nil {
 			log.Panic(err)
 		}
 
-		prevTXs[hex.EncodeToString(prevTX.ID)] = prevTX
+		tx.SignInput(hashes, index, signer, prevTXs, SigHashAll)
 	}
-
-	tx.Sign(privKey, prevTXs)
 }
 
-// Verifies Transaction input signatures
+// Verifies Transaction input signatures and that the transaction, with
+// its lock times, and the spent outputs can be included in the next block
 func (bc *Blockchain) VerifyTransaction(tx *Transaction) bool {
+	return bc.VerifyTransactions([]*Transaction{tx}) == nil
+}
+
+// VerifyTransactions verifies transactions for the next block like
+// VerifyTransaction, checking the inputs of all of them concurrently and
+// their Schnorr signatures in a single batch. No two of them may spend the
+// same output. It returns the first problem found
+func (bc *Blockchain) VerifyTransactions(transactions []*Transaction) error {
+	height := bc.GetBestHeight() + 1
+	blockTime := time.Now().Unix()
+	UTXOSet := UTXOSet{bc}
+	verifier := bc.config.Verifier()
+	checks := []inputCheck{}
+	spent := make(map[string]bool)
+
+	for _, tx := range transactions {
+		if tx.IsCoinbase() {
+			continue
+		}
+
+		if !tx.HasValidWitness() {
+			return fmt.Errorf("Transaction %x has unlocking data outside of the witness", tx.ID)
+		}
+		if !tx.HasValidDataOutputs() {
+			return fmt.Errorf("Transaction %x has an invalid data output", tx.ID)
+		}
+		if !tx.IsFinal(height, blockTime) {
+			return fmt.Errorf("Transaction %x lock time has not elapsed", tx.ID)
+		}
+		if !UTXOSet.IsSpendable(tx, height) {
+			return fmt.Errorf("Transaction %x spends missing or locked outputs", tx.ID)
+		}
+		for _, vin := range tx.Vin {
+			if spent[outpointKey(vin.Txid, vin.Vout)] {
+				return fmt.Errorf("Transaction %x spends an output already spent in the block", tx.ID)
+			}
+			spent[outpointKey(vin.Txid, vin.Vout)] = true
+		}
+		if !tx.HasValidOutputValues() {
+			return fmt.Errorf("Transaction %x has an invalid output value", tx.ID)
+		}
+
+		prevTXs := bc.findPrevTransactions(tx)
+		if tx.Fee(prevTXs) < 0 {
+			return fmt.Errorf("Transaction %x pays more than the outputs it spends", tx.ID)
+		}
+
+		checks = append(checks, newInputChecks(tx, prevTXs, verifier)...)
+	}
+
+	return bc.verifier.VerifyBatch(checks)
+}
+
+// Finds the transactions referenced by the inputs of a Transaction
+func (bc *Blockchain) findPrevTransactions(tx *Transaction) map[string]Transaction {
 	prevTXs := make(map[string]Transaction)
 
 	for _, vin := range tx.Vin {
+		// Find old transactions that the inputs of this new transaction reference
 		prevTX, err := bc.FindTransaction(vin.Txid)
 		if err != nil {
 			log.Panic(err)
@@ -204,7 +424,7 @@ func (bc *Blockchain) VerifyTransaction(tx *Transaction) bool {
 		prevTXs[hex.EncodeToString(prevTX.ID)] = prevTX
 	}
 
-	return tx.Verify(prevTXs)
+	return prevTXs
 }
 
 // NewBlockchain creates a new Blockchain with genesis Block
@@ -215,6 +435,8 @@ func NewBlockchain() *Blockchain {
 	}
 
 	tip := []byte{}
+	config := ChainConfig{}
+	hasConfig := false
 	db, err := bolt.Open(dbFile, 0600, nil)
 	if err != nil {
 		log.Panic(err)
@@ -223,6 +445,7 @@ func NewBlockchain() *Blockchain {
 	err = db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(blocksBucket))
 		tip = b.Get([]byte("l"))
+		config, hasConfig = loadChainConfig(tx)
 
 		return nil
 	})
@@ -231,13 +454,21 @@ func NewBlockchain() *Blockchain {
 		log.Panic(err)
 	}
 
-	bc := &Blockchain{tip, db}
+	// Older chains have no block heights and store the UTXO set in a layout
+	// that can't be read, reindexing them wouldn't bring the heights back
+	if !hasConfig {
+		db.Close()
+		fmt.Println("The blockchain was created by an older version. Delete blockchain.db and run createblockchain again.")
+		os.Exit(1)
+	}
+
+	bc := &Blockchain{tip, db, config, NewScriptVerifier()}
 
 	return bc
 }
 
 // CreateBlockchain creates a new blockchain DB
-func CreateBlockchain(address string) *Blockchain {
+func CreateBlockchain(address string, config ChainConfig) *Blockchain {
 	if dbExists() {
 		fmt.Println("Blockchain already exists.")
 		os.Exit(1)
@@ -252,6 +483,10 @@ func CreateBlockchain(address string) *Blockchain {
 	err = db.Update(func(tx *bolt.Tx) error {
 		cbtx := NewCoinbaseTX(address, genesisCoinbaseData)
 		genesis := NewGenesisBlock(cbtx)
+		err := genesis.Validate()
+		if err != nil {
+			log.Panic(err)
+		}
 
 		b, err := tx.CreateBucket([]byte(blocksBucket))
 		if err != nil {
@@ -268,6 +503,11 @@ func CreateBlockchain(address string) *Blockchain {
 			log.Panic(err)
 		}
 
+		err = saveChainConfig(tx, config)
+		if err != nil {
+			log.Panic(err)
+		}
+
 		tip = genesis.Hash
 		return nil
 	})
@@ -276,7 +516,7 @@ func CreateBlockchain(address string) *Blockchain {
 		log.Panic(err)
 	}
 
-	bc := Blockchain{tip, db}
+	bc := Blockchain{tip, db, config, NewScriptVerifier()}
 
 	return &bc
 }
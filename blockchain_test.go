@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/hex"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates a blockchain in a temporary directory, its genesis coinbase
+// paying wallet and spendable right away. Blocks are mined at a low
+// difficulty
+func newTestBlockchain(t *testing.T, wallet *Wallet) *Blockchain {
+	chdirTemp(t)
+	bits := targetBits
+	targetBits = 8
+
+	config := DefaultChainConfig()
+	config.CoinbaseMaturity = 0
+	bc := CreateBlockchain(string(wallet.GetAddress()), config)
+	UTXOSet{bc}.Reindex()
+
+	t.Cleanup(func() {
+		bc.db.Close()
+		targetBits = bits
+	})
+
+	return bc
+}
+
+// Moves into a temporary directory for the rest of the test, so it can
+// create the blockchain and wallet files
+func chdirTemp(t *testing.T) {
+	dir, err := os.Getwd()
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(t.TempDir()))
+
+	t.Cleanup(func() {
+		os.Chdir(dir)
+	})
+}
+
+// Returns the coinbase transaction of the genesis block
+func genesisCoinbase(bc *Blockchain) *Transaction {
+	bci := bc.Iterator()
+	var block *Block
+	for block = bci.Next(); len(block.PrevBlockHash) != 0; block = bci.Next() {
+	}
+
+	return block.Transactions[0]
+}
+
+// Creates a transaction signed by wallet, spending the outputs of prev at
+// vouts and paying values back to wallet
+func newTestSpend(bc *Blockchain, wallet *Wallet, prev *Transaction, vouts []int, values ...int) *Transaction {
+	inputs := []TXInput{}
+	for _, vout := range vouts {
+		inputs = append(inputs, TXInput{prev.ID, vout, nil, 0})
+	}
+
+	outputs := []TXOutput{}
+	for _, value := range values {
+		outputs = append(outputs, *NewTXOutput(value, string(wallet.GetAddress())))
+	}
+
+	tx := Transaction{nil, inputs, outputs, 0, nil}
+	signer, err := wallet.Signer()
+	if err != nil {
+		panic(err)
+	}
+	bc.SignTransaction(&tx, signer)
+	tx.ID = tx.Hash()
+
+	return &tx
+}
+
+func TestVerifyTransactionsRejectsDoubleSpends(t *testing.T) {
+	wallet := NewWallet(KeyTypeP256)
+	bc := newTestBlockchain(t, wallet)
+	genesis := genesisCoinbase(bc)
+
+	tx := newTestSpend(bc, wallet, genesis, []int{0}, subsidy)
+	assert.Nil(t, bc.VerifyTransactions([]*Transaction{tx}))
+
+	twice := newTestSpend(bc, wallet, genesis, []int{0, 0}, subsidy)
+	assert.NotNil(t, bc.VerifyTransactions([]*Transaction{twice}), "An input listed twice")
+
+	other := newTestSpend(bc, wallet, genesis, []int{0}, subsidy-1)
+	assert.Nil(t, bc.VerifyTransactions([]*Transaction{other}))
+	assert.NotNil(t, bc.VerifyTransactions([]*Transaction{tx, other}), "Two transactions of a block spending the same output")
+}
+
+func TestMineBlockCollectsFees(t *testing.T) {
+	wallet := NewWallet(KeyTypeP256)
+	bc := newTestBlockchain(t, wallet)
+
+	tx := newTestSpend(bc, wallet, genesisCoinbase(bc), []int{0}, subsidy-3)
+	assert.Equal(t, 3, bc.TransactionFee(tx))
+
+	block := bc.MineBlock([]*Transaction{NewCoinbaseTX(string(wallet.GetAddress()), ""), tx})
+	coinbase := block.Transactions[0]
+	assert.Equal(t, subsidy+3, coinbase.Vout[0].Value)
+	assert.Equal(t, coinbase.Hash(), coinbase.ID)
+}
+
+func TestMempoolRejectsInflation(t *testing.T) {
+	wallet := NewWallet(KeyTypeP256)
+	bc := newTestBlockchain(t, wallet)
+	mempool := Mempool{bc}
+	genesis := genesisCoinbase(bc)
+
+	for _, values := range [][]int{
+		{1000000},
+		{subsidy + 1, -1},
+		{math.MaxInt64, math.MaxInt64},
+	} {
+		tx := newTestSpend(bc, wallet, genesis, []int{0}, values...)
+		raw := decodeRawTransaction(hex.EncodeToString(tx.Serialize()))
+		assert.NotNil(t, mempool.Add(&raw), "Outputs %v", values)
+	}
+	assert.Empty(t, mempool.Transactions())
+
+	tx := newTestSpend(bc, wallet, genesis, []int{0}, subsidy-1, 1)
+	assert.Nil(t, mempool.Add(tx))
+}
+
+func TestMempoolRejectsUnconfirmedParents(t *testing.T) {
+	wallet := NewWallet(KeyTypeP256)
+	bc := newTestBlockchain(t, wallet)
+	mempool := Mempool{bc}
+
+	parent := newTestSpend(bc, wallet, genesisCoinbase(bc), []int{0}, subsidy)
+	assert.Nil(t, mempool.Add(parent))
+
+	// The parent isn't on the chain yet, so the child is signed by hand
+	child := Transaction{nil, []TXInput{{parent.ID, 0, nil, 0}}, []TXOutput{*NewTXOutput(subsidy, string(wallet.GetAddress()))}, 0, nil}
+	child.Sign(NewSigner(wallet.PrivateKey), map[string]Transaction{hex.EncodeToString(parent.ID): *parent}, SigHashAll)
+	child.ID = child.Hash()
+	assert.Equal(t, errUnconfirmedInput, mempool.Add(&child))
+
+	block := bc.MineBlock([]*Transaction{NewCoinbaseTX(string(wallet.GetAddress()), ""), parent})
+	UTXOSet{bc}.Update(block)
+	mempool.Update(block)
+	assert.Nil(t, mempool.Add(&child))
+}
+
+func TestBlockValidateWitnessCommitment(t *testing.T) {
+	wallet := NewWallet(KeyTypeP256)
+	bc := newTestBlockchain(t, wallet)
+
+	tx := newTestSpend(bc, wallet, genesisCoinbase(bc), []int{0}, subsidy)
+	block := bc.MineBlock([]*Transaction{NewCoinbaseTX(string(wallet.GetAddress()), ""), tx})
+	assert.Nil(t, block.Validate())
+
+	// Another signature leaves the transaction ID and the proof of work
+	// alone, only the witness commitment catches it
+	tampered := DeserializeBlock(block.Serialize())
+	other := newTestSpend(bc, wallet, genesisCoinbase(bc), []int{0}, subsidy)
+	tampered.Transactions[1].Witness = other.Witness
+	assert.Equal(t, tx.ID, tampered.Transactions[1].Hash())
+	assert.True(t, NewProofOfWork(tampered).Validate())
+	assert.NotNil(t, tampered.Validate())
+}
+
+func TestBlockProofOfWorkCoversHeight(t *testing.T) {
+	wallet := NewWallet(KeyTypeP256)
+	bc := newTestBlockchain(t, wallet)
+
+	block := bc.MineBlock([]*Transaction{NewCoinbaseTX(string(wallet.GetAddress()), "")})
+	assert.Equal(t, 1, block.Height)
+	assert.Nil(t, block.Validate())
+
+	// Coinbase maturity and relative lock times count on the height
+	tampered := DeserializeBlock(block.Serialize())
+	tampered.Height = 100
+	assert.NotNil(t, tampered.Validate())
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"log"
+
+	"github.com/boltdb/bolt"
+)
+
+const configBucket = "config"
+
+// Number of blocks a coinbase output has to wait before it can be spent
+const defaultCoinbaseMaturity = 10
+
+// ChainConfig holds the consensus parameters chosen when the chain is created
+type ChainConfig struct {
+	CoinbaseMaturity int
+	// Type of the keys signatures are checked with, P-256 for chains
+	// created before key types existed
+	KeyType KeyType
+}
+
+// DefaultChainConfig returns the parameters used when none are given
+func DefaultChainConfig() ChainConfig {
+	return ChainConfig{defaultCoinbaseMaturity, KeyTypeP256}
+}
+
+// Verifier returns the Verifier of signatures on the chain
+func (c ChainConfig) Verifier() Verifier {
+	return NewVerifier(c.KeyType)
+}
+
+// Serialize serializes ChainConfig
+func (c ChainConfig) Serialize() []byte {
+	buff := bytes.Buffer{}
+
+	enc := gob.NewEncoder(&buff)
+	err := enc.Encode(c)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return buff.Bytes()
+}
+
+// DeserializeChainConfig deserializes ChainConfig
+func DeserializeChainConfig(data []byte) ChainConfig {
+	config := ChainConfig{}
+
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	err := dec.Decode(&config)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return config
+}
+
+// Reads the chain config from the DB. Chains created before the config
+// was stored don't have one
+func loadChainConfig(tx *bolt.Tx) (ChainConfig, bool) {
+	b := tx.Bucket([]byte(configBucket))
+	if b == nil {
+		return ChainConfig{}, false
+	}
+
+	return DeserializeChainConfig(b.Get([]byte("c"))), true
+}
+
+// Writes the chain config into the DB
+func saveChainConfig(tx *bolt.Tx, config ChainConfig) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(configBucket))
+	if err != nil {
+		return err
+	}
+
+	return b.Put([]byte("c"), config.Serialize())
+}
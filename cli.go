@@ -19,13 +19,146 @@ func (cli *CLI) Run() {
 	createWalletCmd := flag.NewFlagSet("createwallet", flag.ExitOnError)
 	listAddressesCmd := flag.NewFlagSet("listaddresses", flag.ExitOnError)
 	sendCmd := flag.NewFlagSet("send", flag.ExitOnError)
+	sendManyCmd := flag.NewFlagSet("sendmany", flag.ExitOnError)
 	printChainCmd := flag.NewFlagSet("printchain", flag.ExitOnError)
+	mineCmd := flag.NewFlagSet("mine", flag.ExitOnError)
+	createRawTxCmd := flag.NewFlagSet("createrawtransaction", flag.ExitOnError)
+	decodeRawTxCmd := flag.NewFlagSet("decoderawtransaction", flag.ExitOnError)
+	signRawTxCmd := flag.NewFlagSet("signrawtransaction", flag.ExitOnError)
+	sendRawTxCmd := flag.NewFlagSet("sendrawtransaction", flag.ExitOnError)
+	createPSBTCmd := flag.NewFlagSet("createpsbt", flag.ExitOnError)
+	decodePSBTCmd := flag.NewFlagSet("decodepsbt", flag.ExitOnError)
+	signPSBTCmd := flag.NewFlagSet("signpsbt", flag.ExitOnError)
+	combinePSBTCmd := flag.NewFlagSet("combinepsbt", flag.ExitOnError)
+	finalizePSBTCmd := flag.NewFlagSet("finalizepsbt", flag.ExitOnError)
+	createMultisigCmd := flag.NewFlagSet("createmultisig", flag.ExitOnError)
+	getPubKeyCmd := flag.NewFlagSet("getpubkey", flag.ExitOnError)
+	createTimelockCmd := flag.NewFlagSet("createtimelock", flag.ExitOnError)
+	createHTLCCmd := flag.NewFlagSet("createhtlc", flag.ExitOnError)
+	redeemHTLCCmd := flag.NewFlagSet("redeemhtlc", flag.ExitOnError)
+	refundHTLCCmd := flag.NewFlagSet("refundhtlc", flag.ExitOnError)
+	extractPreimageCmd := flag.NewFlagSet("extractpreimage", flag.ExitOnError)
+	sendDataCmd := flag.NewFlagSet("senddata", flag.ExitOnError)
+	findDataCmd := flag.NewFlagSet("finddata", flag.ExitOnError)
+	getXPubCmd := flag.NewFlagSet("getxpub", flag.ExitOnError)
+	deriveAddressesCmd := flag.NewFlagSet("deriveaddresses", flag.ExitOnError)
+	restoreWalletCmd := flag.NewFlagSet("restorewallet", flag.ExitOnError)
+	encryptWalletCmd := flag.NewFlagSet("encryptwallet", flag.ExitOnError)
+	walletPassphraseCmd := flag.NewFlagSet("walletpassphrase", flag.ExitOnError)
+	walletLockCmd := flag.NewFlagSet("walletlock", flag.ExitOnError)
+	dumpPrivKeyCmd := flag.NewFlagSet("dumpprivkey", flag.ExitOnError)
+	importPrivKeyCmd := flag.NewFlagSet("importprivkey", flag.ExitOnError)
+	dumpWalletCmd := flag.NewFlagSet("dumpwallet", flag.ExitOnError)
+	importWalletCmd := flag.NewFlagSet("importwallet", flag.ExitOnError)
+	importAddressCmd := flag.NewFlagSet("importaddress", flag.ExitOnError)
+	listUnspentCmd := flag.NewFlagSet("listunspent", flag.ExitOnError)
+	listTransactionsCmd := flag.NewFlagSet("listtransactions", flag.ExitOnError)
+	getWalletInfoCmd := flag.NewFlagSet("getwalletinfo", flag.ExitOnError)
+	setLabelCmd := flag.NewFlagSet("setlabel", flag.ExitOnError)
+	setAccountCmd := flag.NewFlagSet("setaccount", flag.ExitOnError)
 
-	getBalanceAddress := getBalanceCmd.String("address", "", "The address to get balance for")
+	getBalanceAddress := getBalanceCmd.String("address", "", "The address to get balance for, every wallet address when empty")
 	createBlockchainAddress := createBlockchainCmd.String("address", "", "The address to send genesis block reward to")
-	sendFrom := sendCmd.String("from", "", "Source wallet address")
+	createBlockchainMaturity := createBlockchainCmd.Int("maturity", defaultCoinbaseMaturity, "Number of blocks before a coinbase output can be spent")
+	createBlockchainKeyType := createBlockchainCmd.String("keytype", KeyTypeP256.String(), "Type of the keys signing transactions: p256 or secp256k1")
+	createWalletKeyType := createWalletCmd.String("keytype", KeyTypeP256.String(), "Type of the new key: p256 or secp256k1")
+	createWalletMnemonic := createWalletCmd.Bool("mnemonic", false, "Derive the keys from a new mnemonic, for a wallet without keys yet")
+	createWalletWords := createWalletCmd.Int("words", 12, "Number of words of the mnemonic: 12, 15, 18, 21 or 24")
+	createWalletPassphrase := createWalletCmd.String("passphrase", "", "Optional passphrase extending the mnemonic")
+	createWalletLabel := createWalletCmd.String("label", "", "Label of the new address")
+	createWalletAccount := createWalletCmd.String("account", defaultAccount, "Account of the new address")
+	sendFrom := sendCmd.String("from", "", "Comma separated source wallet addresses, all wallet addresses when empty")
+	sendChange := sendCmd.String("change", "", "Address receiving the change, the first source address when empty")
 	sendTo := sendCmd.String("to", "", "Destination wallet address")
 	sendAmount := sendCmd.Int("amount", 0, "Amount to send")
+	sendStrategy := sendCmd.String("strategy", "largest", "Coin selection strategy: largest, smallest, bnb or random")
+	sendFee := sendCmd.Int("fee", 0, "Fee paid for every input")
+	sendManyFrom := sendManyCmd.String("from", "", "Comma separated source wallet addresses, all wallet addresses when empty")
+	sendManyChange := sendManyCmd.String("change", "", "Address receiving the change, the first source address when empty")
+	sendManyTo := recipientsFlag{}
+	sendManyCmd.Var(&sendManyTo, "to", "Recipient as ADDRESS:AMOUNT, can be repeated")
+	sendManyFile := sendManyCmd.String("file", "", "JSON file with a list of {\"address\", \"amount\"} recipients")
+	sendManyStrategy := sendManyCmd.String("strategy", "largest", "Coin selection strategy: largest, smallest, bnb or random")
+	sendManyFee := sendManyCmd.Int("fee", 0, "Fee paid for every input")
+	mineAddress := mineCmd.String("address", "", "The address to send the block reward to")
+	createRawTxInputs := outpointsFlag{}
+	createRawTxCmd.Var(&createRawTxInputs, "in", "Output to spend as TXID:VOUT[:SEQUENCE], can be repeated. SEQUENCE is a relative lock time in blocks")
+	createRawTxOutputs := recipientsFlag{}
+	createRawTxCmd.Var(&createRawTxOutputs, "out", "Output to create as ADDRESS:AMOUNT, can be repeated")
+	createRawTxLockTime := createRawTxCmd.Int("locktime", 0, "Block height or unix time before which the transaction can't be mined")
+	decodeRawTxHex := decodeRawTxCmd.String("hex", "", "Hex encoded transaction")
+	signRawTxHex := signRawTxCmd.String("hex", "", "Hex encoded transaction")
+	signRawTxSigHash := signRawTxCmd.String("sighash", "ALL", "Signature hash type: ALL, NONE or SINGLE, optionally with |ANYONECANPAY")
+	signRawTxSigType := signRawTxCmd.String("sigtype", SigTypeECDSA.String(), "Signature type: ecdsa, or schnorr for secp256k1 keys")
+	sendRawTxHex := sendRawTxCmd.String("hex", "", "Hex encoded transaction")
+	sendRawTxMine := sendRawTxCmd.Bool("mine", false, "Mine the pool right away instead of only adding the transaction to it")
+	sendRawTxAddress := sendRawTxCmd.String("address", "", "The address to send the block reward to when mining")
+	createPSBTInputs := outpointsFlag{}
+	createPSBTCmd.Var(&createPSBTInputs, "in", "Output to spend as TXID:VOUT[:SEQUENCE], can be repeated. SEQUENCE is a relative lock time in blocks")
+	createPSBTOutputs := recipientsFlag{}
+	createPSBTCmd.Var(&createPSBTOutputs, "out", "Output to create as ADDRESS:AMOUNT, can be repeated")
+	createPSBTFrom := createPSBTCmd.String("from", "", "Address to pick inputs from and send change to, instead of -in")
+	createPSBTFee := createPSBTCmd.Int("fee", 0, "Fee paid for every input picked with -from")
+	createPSBTLockTime := createPSBTCmd.Int("locktime", 0, "Block height or unix time before which the transaction can't be mined")
+	createPSBTSequence := createPSBTCmd.Int("sequence", 0, "Relative lock time in blocks of the inputs picked with -from")
+	createPSBTFile := createPSBTCmd.String("file", "", "File to write the partially signed transaction to")
+	decodePSBTFile := decodePSBTCmd.String("file", "", "Partially signed transaction file")
+	signPSBTFile := signPSBTCmd.String("file", "", "Partially signed transaction file")
+	signPSBTOut := signPSBTCmd.String("out", "", "File to write the result to, the input file when empty")
+	signPSBTSigHash := signPSBTCmd.String("sighash", "ALL", "Signature hash type: ALL, NONE or SINGLE, optionally with |ANYONECANPAY")
+	signPSBTSigType := signPSBTCmd.String("sigtype", SigTypeECDSA.String(), "Signature type: ecdsa, or schnorr for secp256k1 keys")
+	combinePSBTFiles := combinePSBTCmd.String("files", "", "Comma separated partially signed transaction files")
+	combinePSBTOut := combinePSBTCmd.String("out", "", "File to write the result to")
+	finalizePSBTFile := finalizePSBTCmd.String("file", "", "Partially signed transaction file")
+	createMultisigM := createMultisigCmd.Int("m", 0, "Number of required signatures")
+	createMultisigPubKeys := createMultisigCmd.String("pubkeys", "", "Comma separated hex encoded public keys")
+	getPubKeyAddress := getPubKeyCmd.String("address", "", "Wallet address to print the public key of")
+	getPubKeyUncompressed := getPubKeyCmd.Bool("uncompressed", false, "Print the uncompressed form of the public key")
+	createTimelockAddress := createTimelockCmd.String("address", "", "Address paid once the lock time has elapsed")
+	createTimelockLockTime := createTimelockCmd.Int("locktime", 0, "Block height or unix time, or number of blocks with -relative")
+	createTimelockRelative := createTimelockCmd.Bool("relative", false, "Lock outputs for a number of blocks after they are mined")
+	createHTLCRecipient := createHTLCCmd.String("recipient", "", "Address that can redeem the contract with the secret")
+	createHTLCSender := createHTLCCmd.String("sender", "", "Address that can refund the contract after the timeout")
+	createHTLCHash := createHTLCCmd.String("hash", "", "Hex SHA-256 hash of the 32 byte secret, a new secret is generated when empty")
+	createHTLCTimeout := createHTLCCmd.Int("timeout", 0, "Block height or unix time from which the sender can refund")
+	redeemHTLCAddress := redeemHTLCCmd.String("address", "", "HTLC address of the wallet")
+	redeemHTLCPreimage := redeemHTLCCmd.String("preimage", "", "Hex encoded secret")
+	redeemHTLCTo := redeemHTLCCmd.String("to", "", "Address receiving the contract outputs")
+	redeemHTLCFee := redeemHTLCCmd.Int("fee", 0, "Fee paid for every input")
+	refundHTLCAddress := refundHTLCCmd.String("address", "", "HTLC address of the wallet")
+	refundHTLCTo := refundHTLCCmd.String("to", "", "Address receiving the contract outputs")
+	refundHTLCFee := refundHTLCCmd.Int("fee", 0, "Fee paid for every input")
+	extractPreimageTxid := extractPreimageCmd.String("txid", "", "ID of a transaction redeeming an HTLC")
+	sendDataFrom := sendDataCmd.String("from", "", "Wallet address paying for the data")
+	sendDataHex := sendDataCmd.String("hex", "", "Hex encoded data, up to 80 bytes")
+	sendDataFee := sendDataCmd.Int("fee", 0, "Fee paid for the input")
+	findDataPrefix := findDataCmd.String("prefix", "", "Hex encoded prefix of the data, all data outputs when empty")
+	getXPubKeyType := getXPubCmd.String("keytype", KeyTypeP256.String(), "Type of the keys: p256 or secp256k1")
+	deriveAddressesXPub := deriveAddressesCmd.String("xpub", "", "Extended public key of an account")
+	deriveAddressesStart := deriveAddressesCmd.Int("start", 0, "Index of the first address")
+	deriveAddressesCount := deriveAddressesCmd.Int("count", 10, "Number of addresses")
+	restoreWalletMnemonic := restoreWalletCmd.String("mnemonic", "", "Mnemonic of the wallet to restore")
+	restoreWalletPassphrase := restoreWalletCmd.String("passphrase", "", "Passphrase given with the mnemonic")
+	restoreWalletGap := restoreWalletCmd.Int("gap", 20, "Number of unused addresses in a row after which the rescan stops")
+	encryptWalletPassphrase := encryptWalletCmd.String("passphrase", "", "Passphrase encrypting the private keys")
+	walletPassphrasePassphrase := walletPassphraseCmd.String("passphrase", "", "Passphrase of the wallet")
+	walletPassphraseTimeout := walletPassphraseCmd.Int("timeout", 300, "Number of seconds the wallet stays unlocked")
+	dumpPrivKeyAddress := dumpPrivKeyCmd.String("address", "", "Address of the key")
+	importPrivKeyKey := importPrivKeyCmd.String("key", "", "Private key in the Wallet Import Format")
+	importPrivKeyRescan := importPrivKeyCmd.Bool("rescan", true, "Print the balance of the key found in the UTXO set")
+	dumpWalletFile := dumpWalletCmd.String("file", "", "File to write the wallet dump to")
+	importWalletFile := importWalletCmd.String("file", "", "Wallet dump file")
+	importWalletRescan := importWalletCmd.Bool("rescan", true, "Print the balances of the keys found in the UTXO set")
+	importAddressAddress := importAddressCmd.String("address", "", "Address to watch")
+	importAddressPubKey := importAddressCmd.String("pubkey", "", "Hex public key of the address to watch")
+	importAddressRescan := importAddressCmd.Bool("rescan", true, "Print the balance of the address found in the UTXO set")
+	listUnspentAddress := listUnspentCmd.String("address", "", "Wallet address to list the outputs of, every wallet address when empty")
+	listTransactionsAddress := listTransactionsCmd.String("address", "", "Wallet address to list the transactions of, every wallet address when empty")
+	setLabelAddress := setLabelCmd.String("address", "", "Wallet address to label")
+	setLabelLabel := setLabelCmd.String("label", "", "Label of the address, removed when empty")
+	setAccountAddress := setAccountCmd.String("address", "", "Wallet address to move")
+	setAccountAccount := setAccountCmd.String("account", defaultAccount, "Account to move the address to")
+	listAddressesAccount := listAddressesCmd.String("account", "", "Account to list the addresses of, every account when empty")
 
 	switch os.Args[1] {
 	case "getbalance":
@@ -58,6 +191,186 @@ func (cli *CLI) Run() {
 		if err != nil {
 			log.Panic(err)
 		}
+	case "sendmany":
+		err := sendManyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "mine":
+		err := mineCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "createrawtransaction":
+		err := createRawTxCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "decoderawtransaction":
+		err := decodeRawTxCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "signrawtransaction":
+		err := signRawTxCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "sendrawtransaction":
+		err := sendRawTxCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "createpsbt":
+		err := createPSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "decodepsbt":
+		err := decodePSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "signpsbt":
+		err := signPSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "combinepsbt":
+		err := combinePSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "finalizepsbt":
+		err := finalizePSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "createmultisig":
+		err := createMultisigCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "getpubkey":
+		err := getPubKeyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "createtimelock":
+		err := createTimelockCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "createhtlc":
+		err := createHTLCCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "redeemhtlc":
+		err := redeemHTLCCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "refundhtlc":
+		err := refundHTLCCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "extractpreimage":
+		err := extractPreimageCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "senddata":
+		err := sendDataCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "finddata":
+		err := findDataCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "getxpub":
+		err := getXPubCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "deriveaddresses":
+		err := deriveAddressesCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "restorewallet":
+		err := restoreWalletCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "encryptwallet":
+		err := encryptWalletCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "walletpassphrase":
+		err := walletPassphraseCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "walletlock":
+		err := walletLockCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "dumpprivkey":
+		err := dumpPrivKeyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "importprivkey":
+		err := importPrivKeyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "dumpwallet":
+		err := dumpWalletCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "importwallet":
+		err := importWalletCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "importaddress":
+		err := importAddressCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "listunspent":
+		err := listUnspentCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "listtransactions":
+		err := listTransactionsCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "getwalletinfo":
+		err := getWalletInfoCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "setlabel":
+		err := setLabelCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "setaccount":
+		err := setAccountCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
 	case "deletechain":
 		DeleteBlockchain()
 		os.Exit(1)
@@ -67,10 +380,6 @@ func (cli *CLI) Run() {
 	}
 
 	if getBalanceCmd.Parsed() {
-		if *getBalanceAddress == "" {
-			getBalanceCmd.Usage()
-			os.Exit(1)
-		}
 		cli.getBalance(*getBalanceAddress)
 	}
 
@@ -79,15 +388,15 @@ func (cli *CLI) Run() {
 			createBlockchainCmd.Usage()
 			os.Exit(1)
 		}
-		cli.createBlockchain(*createBlockchainAddress)
+		cli.createBlockchain(*createBlockchainAddress, *createBlockchainMaturity, *createBlockchainKeyType)
 	}
 
 	if createWalletCmd.Parsed() {
-		cli.createWallet()
+		cli.createWallet(*createWalletKeyType, *createWalletMnemonic, *createWalletWords, *createWalletPassphrase, *createWalletLabel, *createWalletAccount)
 	}
 
 	if listAddressesCmd.Parsed() {
-		cli.listAddresses()
+		cli.listAddresses(*listAddressesAccount)
 	}
 
 	if printChainCmd.Parsed() {
@@ -95,12 +404,302 @@ func (cli *CLI) Run() {
 	}
 
 	if sendCmd.Parsed() {
-		if *sendFrom == "" || *sendTo == "" || *sendAmount <= 0 {
+		if *sendTo == "" || *sendAmount <= 0 {
 			sendCmd.Usage()
 			os.Exit(1)
 		}
 
-		cli.send(*sendFrom, *sendTo, *sendAmount)
+		cli.send(splitList(*sendFrom), *sendChange, *sendTo, *sendAmount, *sendStrategy, *sendFee)
+	}
+
+	if sendManyCmd.Parsed() {
+		recipients := []Recipient(sendManyTo)
+		if *sendManyFile != "" {
+			recipients = append(recipients, loadRecipients(*sendManyFile)...)
+		}
+
+		if len(recipients) == 0 {
+			sendManyCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.sendMany(splitList(*sendManyFrom), *sendManyChange, recipients, *sendManyStrategy, *sendManyFee)
+	}
+
+	if mineCmd.Parsed() {
+		if *mineAddress == "" {
+			mineCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.mine(*mineAddress)
+	}
+
+	if createRawTxCmd.Parsed() {
+		if len(createRawTxInputs) == 0 || len(createRawTxOutputs) == 0 {
+			createRawTxCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.createRawTransaction(createRawTxInputs, createRawTxOutputs, *createRawTxLockTime)
+	}
+
+	if decodeRawTxCmd.Parsed() {
+		if *decodeRawTxHex == "" {
+			decodeRawTxCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.decodeRawTransaction(*decodeRawTxHex)
+	}
+
+	if signRawTxCmd.Parsed() {
+		if *signRawTxHex == "" {
+			signRawTxCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.signRawTransaction(*signRawTxHex, *signRawTxSigHash, *signRawTxSigType)
+	}
+
+	if sendRawTxCmd.Parsed() {
+		if *sendRawTxHex == "" || (*sendRawTxMine && *sendRawTxAddress == "") {
+			sendRawTxCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.sendRawTransaction(*sendRawTxHex, *sendRawTxMine, *sendRawTxAddress)
+	}
+
+	if createPSBTCmd.Parsed() {
+		if (len(createPSBTInputs) == 0) == (*createPSBTFrom == "") || len(createPSBTOutputs) == 0 || *createPSBTFile == "" {
+			createPSBTCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.createPSBT(createPSBTInputs, createPSBTOutputs, *createPSBTFrom, *createPSBTFee, *createPSBTLockTime, *createPSBTSequence, *createPSBTFile)
+	}
+
+	if decodePSBTCmd.Parsed() {
+		if *decodePSBTFile == "" {
+			decodePSBTCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.decodePSBT(*decodePSBTFile)
+	}
+
+	if signPSBTCmd.Parsed() {
+		if *signPSBTFile == "" {
+			signPSBTCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.signPSBT(*signPSBTFile, *signPSBTOut, *signPSBTSigHash, *signPSBTSigType)
+	}
+
+	if combinePSBTCmd.Parsed() {
+		files := splitList(*combinePSBTFiles)
+		if len(files) == 0 || *combinePSBTOut == "" {
+			combinePSBTCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.combinePSBT(files, *combinePSBTOut)
+	}
+
+	if finalizePSBTCmd.Parsed() {
+		if *finalizePSBTFile == "" {
+			finalizePSBTCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.finalizePSBT(*finalizePSBTFile)
+	}
+
+	if createMultisigCmd.Parsed() {
+		pubKeys := splitList(*createMultisigPubKeys)
+		if *createMultisigM == 0 || len(pubKeys) == 0 {
+			createMultisigCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.createMultisig(*createMultisigM, pubKeys)
+	}
+
+	if getPubKeyCmd.Parsed() {
+		if *getPubKeyAddress == "" {
+			getPubKeyCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.getPubKey(*getPubKeyAddress, *getPubKeyUncompressed)
+	}
+
+	if createTimelockCmd.Parsed() {
+		if *createTimelockAddress == "" || *createTimelockLockTime == 0 {
+			createTimelockCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.createTimelock(*createTimelockAddress, *createTimelockLockTime, *createTimelockRelative)
+	}
+
+	if createHTLCCmd.Parsed() {
+		if *createHTLCRecipient == "" || *createHTLCSender == "" || *createHTLCTimeout == 0 {
+			createHTLCCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.createHTLC(*createHTLCRecipient, *createHTLCSender, *createHTLCHash, *createHTLCTimeout)
+	}
+
+	if redeemHTLCCmd.Parsed() {
+		if *redeemHTLCAddress == "" || *redeemHTLCPreimage == "" || *redeemHTLCTo == "" {
+			redeemHTLCCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.redeemHTLC(*redeemHTLCAddress, *redeemHTLCPreimage, *redeemHTLCTo, *redeemHTLCFee)
+	}
+
+	if refundHTLCCmd.Parsed() {
+		if *refundHTLCAddress == "" || *refundHTLCTo == "" {
+			refundHTLCCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.refundHTLC(*refundHTLCAddress, *refundHTLCTo, *refundHTLCFee)
+	}
+
+	if extractPreimageCmd.Parsed() {
+		if *extractPreimageTxid == "" {
+			extractPreimageCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.extractPreimage(*extractPreimageTxid)
+	}
+
+	if sendDataCmd.Parsed() {
+		if *sendDataFrom == "" || *sendDataHex == "" {
+			sendDataCmd.Usage()
+			os.Exit(1)
+		}
+
+		cli.sendData(*sendDataFrom, *sendDataHex, *sendDataFee)
+	}
+
+	if findDataCmd.Parsed() {
+		cli.findData(*findDataPrefix)
+	}
+
+	if getXPubCmd.Parsed() {
+		cli.getXPub(*getXPubKeyType)
+	}
+
+	if deriveAddressesCmd.Parsed() {
+		if *deriveAddressesXPub == "" {
+			deriveAddressesCmd.Usage()
+			os.Exit(1)
+		}
+		cli.deriveAddresses(*deriveAddressesXPub, *deriveAddressesStart, *deriveAddressesCount)
+	}
+
+	if restoreWalletCmd.Parsed() {
+		if *restoreWalletMnemonic == "" {
+			restoreWalletCmd.Usage()
+			os.Exit(1)
+		}
+		cli.restoreWallet(*restoreWalletMnemonic, *restoreWalletPassphrase, *restoreWalletGap)
+	}
+
+	if encryptWalletCmd.Parsed() {
+		if *encryptWalletPassphrase == "" {
+			encryptWalletCmd.Usage()
+			os.Exit(1)
+		}
+		cli.encryptWallet(*encryptWalletPassphrase)
+	}
+
+	if walletPassphraseCmd.Parsed() {
+		if *walletPassphrasePassphrase == "" {
+			walletPassphraseCmd.Usage()
+			os.Exit(1)
+		}
+		cli.walletPassphrase(*walletPassphrasePassphrase, *walletPassphraseTimeout)
+	}
+
+	if walletLockCmd.Parsed() {
+		cli.walletLock()
+	}
+
+	if dumpPrivKeyCmd.Parsed() {
+		if *dumpPrivKeyAddress == "" {
+			dumpPrivKeyCmd.Usage()
+			os.Exit(1)
+		}
+		cli.dumpPrivKey(*dumpPrivKeyAddress)
+	}
+
+	if importPrivKeyCmd.Parsed() {
+		if *importPrivKeyKey == "" {
+			importPrivKeyCmd.Usage()
+			os.Exit(1)
+		}
+		cli.importPrivKey(*importPrivKeyKey, *importPrivKeyRescan)
+	}
+
+	if dumpWalletCmd.Parsed() {
+		if *dumpWalletFile == "" {
+			dumpWalletCmd.Usage()
+			os.Exit(1)
+		}
+		cli.dumpWallet(*dumpWalletFile)
+	}
+
+	if importWalletCmd.Parsed() {
+		if *importWalletFile == "" {
+			importWalletCmd.Usage()
+			os.Exit(1)
+		}
+		cli.importWallet(*importWalletFile, *importWalletRescan)
+	}
+
+	if importAddressCmd.Parsed() {
+		if (*importAddressAddress == "") == (*importAddressPubKey == "") {
+			importAddressCmd.Usage()
+			os.Exit(1)
+		}
+		cli.importAddress(*importAddressAddress, *importAddressPubKey, *importAddressRescan)
+	}
+
+	if listUnspentCmd.Parsed() {
+		cli.listUnspent(*listUnspentAddress)
+	}
+
+	if listTransactionsCmd.Parsed() {
+		cli.listTransactions(*listTransactionsAddress)
+	}
+
+	if getWalletInfoCmd.Parsed() {
+		cli.getWalletInfo()
+	}
+
+	if setLabelCmd.Parsed() {
+		if *setLabelAddress == "" {
+			setLabelCmd.Usage()
+			os.Exit(1)
+		}
+		cli.setLabel(*setLabelAddress, *setLabelLabel)
+	}
+
+	if setAccountCmd.Parsed() {
+		if *setAccountAddress == "" {
+			setAccountCmd.Usage()
+			os.Exit(1)
+		}
+		cli.setAccount(*setAccountAddress, *setAccountAccount)
 	}
 }
 
@@ -109,14 +708,56 @@ func (cli *CLI) validateArgs() {
 		cli.printUsage()
 		os.Exit(1)
 	}
+
+	_, err := envVerifyWorkers()
+	if err != nil {
+		log.Panicf("ERROR: %s", err)
+	}
 }
 
 func (cli *CLI) printUsage() {
 	fmt.Println("Usage:")
-	fmt.Println("  createblockchain -address ADDRESS - Create a blockchain and send genesis block reward to ADDRESS")
-	fmt.Println("  createwallet - Generates a new key-pair and saves it into the wallet file")
-	fmt.Println("  getbalance -address ADDRESS - Get balance of ADDRESS")
-	fmt.Println("  listaddresses - Lists all addresses from the wallet file")
+	fmt.Println("  createblockchain -address ADDRESS [-maturity N] [-keytype TYPE] - Create a blockchain and send genesis block reward to ADDRESS. Coinbase outputs can be spent after N blocks, and only keys of TYPE can sign transactions")
+	fmt.Println("  combinepsbt -files FILE,... -out FILE - Merge the signatures of partially signed transaction files")
+	fmt.Println("  createhtlc -recipient ADDRESS -sender ADDRESS -timeout LOCKTIME [-hash HASH] - Create a hash time-locked contract address redeemable by the recipient with the 32 byte secret of HASH, or refundable by the sender from block height or unix time LOCKTIME. A secret is generated when HASH is omitted")
+	fmt.Println("  createmultisig -m M -pubkeys PUBKEY,... - Create an M-of-N multisig address from hex public keys and add it to the wallet file")
+	fmt.Println("  createpsbt (-in TXID:VOUT[:SEQUENCE] ... | -from ADDRESS [-fee FEE] [-sequence N]) -out ADDRESS:AMOUNT ... [-locktime LOCKTIME] -file FILE - Create a partially signed transaction that can be signed offline")
+	fmt.Println("  createrawtransaction -in TXID:VOUT[:SEQUENCE] ... -out ADDRESS:AMOUNT ... [-locktime LOCKTIME] - Create an unsigned transaction and print it hex encoded. It can't be mined before block height or unix time LOCKTIME")
+	fmt.Println("  createtimelock -address ADDRESS -locktime LOCKTIME [-relative] - Create an address paying to ADDRESS once block height or unix time LOCKTIME is reached, or LOCKTIME blocks after funding with -relative, and add it to the wallet file")
+	fmt.Println("  createwallet [-keytype TYPE] [-mnemonic [-words N] [-passphrase PASSPHRASE]] [-label LABEL] [-account ACCOUNT] - Generates a new p256 or secp256k1 key-pair and saves it into the wallet file. With -mnemonic, a new wallet derives its keys from a printed N word mnemonic")
+	fmt.Println("  decodepsbt -file FILE - Print a partially signed transaction")
+	fmt.Println("  decoderawtransaction -hex HEX - Print a hex encoded transaction")
+	fmt.Println("  deriveaddresses -xpub XPUB [-start N] [-count N] - Print the addresses derived from an account extended public key, without private keys")
+	fmt.Println("  dumpprivkey -address ADDRESS - Print the private key of a wallet address in the Wallet Import Format")
+	fmt.Println("  dumpwallet -file FILE - Write the seed, every private key and redeem script of the wallet to a text FILE")
+	fmt.Println("  encryptwallet -passphrase PASSPHRASE - Encrypt the private keys of the wallet file. Signing then needs walletpassphrase first")
+	fmt.Println("  extractpreimage -txid TXID - Print the secret revealed by a transaction redeeming an HTLC")
+	fmt.Println("  finalizepsbt -file FILE - Print a fully signed partially signed transaction as a raw transaction hex")
+	fmt.Println("  finddata [-prefix PREFIX] - List the data outputs of the blockchain starting with the hex PREFIX")
+	fmt.Println("  getbalance [-address ADDRESS] - Get spendable and immature balance of ADDRESS, or of the whole wallet with its watch-only balance apart")
+	fmt.Println("  getpubkey -address ADDRESS [-uncompressed] - Print the hex public key of a wallet address, compressed by default")
+	fmt.Println("  getwalletinfo - Print the number of addresses, the encryption state and the balances of the wallet and of its accounts")
+	fmt.Println("  getxpub [-keytype TYPE] - Print the extended public key of the wallet account deriving the addresses of TYPE")
+	fmt.Println("  importaddress (-address ADDRESS | -pubkey PUBKEY) [-rescan=false] - Watch an address without its private key, its coins are counted but can't be spent")
+	fmt.Println("  importprivkey -key KEY [-rescan=false] - Add a private key in the Wallet Import Format to the wallet file and print its balance")
+	fmt.Println("  importwallet -file FILE [-rescan=false] - Add the keys and redeem scripts of a dumpwallet FILE to the wallet file and print their balances")
+	fmt.Println("  listaddresses [-account ACCOUNT] - Lists all addresses from the wallet file by account with their balance and label, including multisig, timelocked, HTLC and watch-only ones")
+	fmt.Println("  listtransactions [-address ADDRESS] - List the transactions paying to or spending from the wallet addresses, watch-only ones included")
+	fmt.Println("  listunspent [-address ADDRESS] - List the unspent outputs of the wallet addresses, watch-only ones included")
+	fmt.Println("  mine -address ADDRESS - Mine a block with the pooled transactions and send the reward and their fees to ADDRESS")
 	fmt.Println("  printchain - Print all the blocks of the blockchain")
-	fmt.Println("  send -from FROM -to TO -amount AMOUNT - Send AMOUNT of coins from FROM address to TO")
+	fmt.Println("  redeemhtlc -address ADDRESS -preimage SECRET -to TO [-fee FEE] - Send the outputs of an HTLC address to TO with the secret and the recipient key")
+	fmt.Println("  refundhtlc -address ADDRESS -to TO [-fee FEE] - Send the outputs of an HTLC address back to TO with the sender key once the timeout is reached")
+	fmt.Println("  restorewallet -mnemonic \"WORDS\" [-passphrase PASSPHRASE] [-gap N] - Rebuild the wallet file from a mnemonic, rescanning the blockchain for addresses that received coins until N unused ones in a row")
+	fmt.Println("  send [-from FROM,...] [-change CHANGE] -to TO -amount AMOUNT [-strategy STRATEGY] [-fee FEE] - Send AMOUNT of coins from FROM addresses (all wallet addresses by default) to TO, picking inputs with STRATEGY (largest, smallest, bnb, random) and paying FEE per input")
+	fmt.Println("  senddata -from FROM -hex DATA [-fee FEE] - Embed up to 80 bytes of hex DATA in the blockchain in an unspendable output paid for by FROM")
+	fmt.Println("  sendmany [-from FROM,...] [-change CHANGE] [-to ADDRESS:AMOUNT ...] [-file FILE] [-strategy STRATEGY] [-fee FEE] - Pay every recipient given with -to or listed in the JSON FILE in a single transaction")
+	fmt.Println("  sendrawtransaction -hex HEX [-mine -address ADDRESS] - Add a signed transaction spending confirmed outputs to the pool, optionally mining it right away")
+	fmt.Println("  setaccount -address ADDRESS [-account ACCOUNT] - Move a wallet address to ACCOUNT, the default one when omitted")
+	fmt.Println("  setlabel -address ADDRESS [-label LABEL] - Label a wallet address, an empty LABEL removes the label")
+	fmt.Println("  signpsbt -file FILE [-out FILE] [-sighash TYPE] [-sigtype SIGTYPE] - Sign a partially signed transaction with keys from the wallet file, without the blockchain")
+	fmt.Println("  signrawtransaction -hex HEX [-sighash TYPE] [-sigtype SIGTYPE] - Sign the inputs of a hex encoded transaction with keys from the wallet file. TYPE is ALL, NONE or SINGLE, optionally with |ANYONECANPAY. SIGTYPE is ecdsa, or schnorr for secp256k1 keys")
+	fmt.Println("  walletlock - Forget the key of an unlocked wallet right away")
+	fmt.Println("  walletpassphrase -passphrase PASSPHRASE [-timeout SECONDS] - Unlock an encrypted wallet for the commands run in the next SECONDS. Its key is stored unencrypted in wallet.unlock, so anyone who can read that file can spend from the wallet, until walletlock or the first command run after the timeout removes it")
+	fmt.Printf("Signatures are verified by one worker per CPU, set %s to change it\n", verifyWorkersEnv)
 }
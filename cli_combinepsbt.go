@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) combinePSBT(files []string, out string) {
+	ptx := LoadPartialTransaction(files[0])
+
+	for _, file := range files[1:] {
+		err := ptx.Combine(LoadPartialTransaction(file))
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	ptx.SaveToFile(out)
+
+	fmt.Printf("Combined %d partially signed transactions into %s\n", len(files), out)
+}
@@ -5,11 +5,24 @@ import (
 	"log"
 )
 
-func (cli *CLI) createBlockchain(address string) {
+func (cli *CLI) createBlockchain(address string, maturity int, keyTypeName string) {
 	if !ValidateAddress(address) {
 		log.Panic("ERROR: Address is not valid")
 	}
-	bc := CreateBlockchain(address)
+	if maturity < 0 {
+		log.Panic("ERROR: Coinbase maturity can't be negative")
+	}
+
+	keyType, err := ParseKeyType(keyTypeName)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	config := DefaultChainConfig()
+	config.CoinbaseMaturity = maturity
+	config.KeyType = keyType
+
+	bc := CreateBlockchain(address, config)
 	defer bc.db.Close()
 
 	// Reindexing happens only right after a new blockchain is created
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) createHTLC(recipient, sender, hashHex string, timeout int) {
+	if !ValidateAddress(recipient) || !ValidateAddress(sender) {
+		log.Panic("ERROR: Address is not valid")
+	}
+	recipientHash := AddressScript(recipient).PubKeyHash()
+	senderHash := AddressScript(sender).PubKeyHash()
+	if recipientHash == nil || senderHash == nil {
+		log.Panic("ERROR: Recipient and sender must be key addresses")
+	}
+	if timeout <= 0 {
+		log.Panic("ERROR: Timeout must be positive")
+	}
+
+	// Without a hash a new secret is generated
+	var hash []byte
+	if hashHex == "" {
+		secret := make([]byte, htlcPreimageLen)
+		_, err := rand.Read(secret)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		sum := sha256.Sum256(secret)
+		hash = sum[:]
+		fmt.Printf("Secret: %x\n", secret)
+	} else {
+		var err error
+		hash, err = hex.DecodeString(hashHex)
+		if err != nil || len(hash) != sha256.Size {
+			log.Panic("ERROR: Hash must be a hex encoded SHA-256 hash")
+		}
+	}
+
+	wallets, _ := NewWallets()
+	address := wallets.AddHTLC(HTLC{hash, recipientHash, senderHash, timeout})
+	wallets.SaveToFile()
+
+	fmt.Printf("Hash: %x\n", hash)
+	fmt.Printf("Your new HTLC address: %s\n", address)
+	fmt.Printf("Redeem script: %x\n", []byte(wallets.Scripts[address]))
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) createMultisig(m int, pubKeysHex []string) {
+	if m < 1 || m > len(pubKeysHex) || len(pubKeysHex) > maxMultisigKeys {
+		log.Panicf("ERROR: Multisig needs 1 <= M <= N <= %d", maxMultisigKeys)
+	}
+
+	pubKeys := [][]byte{}
+	for _, pubKeyHex := range pubKeysHex {
+		pubKey, err := hex.DecodeString(pubKeyHex)
+		if err != nil {
+			log.Panic(err)
+		}
+		if _, err := ParsePubKey(pubKey); err != nil {
+			log.Panicf("ERROR: Invalid public key %s: %s", pubKeyHex, err)
+		}
+		pubKeys = append(pubKeys, pubKey)
+	}
+
+	wallets, _ := NewWallets()
+	address := wallets.AddMultisig(m, pubKeys)
+	wallets.SaveToFile()
+
+	fmt.Printf("Your new %d-of-%d multisig address: %s\n", m, len(pubKeys), address)
+	fmt.Printf("Redeem script: %x\n", []byte(wallets.Scripts[address]))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) createPSBT(inputs []TXInput, recipients []Recipient, from string, fee, lockTime, sequence int, file string) {
+	outputs := []TXOutput{}
+
+	for _, recipient := range recipients {
+		if !ValidateAddress(recipient.Address) {
+			log.Panicf("ERROR: Recipient address '%s' is not valid", recipient.Address)
+		}
+		if recipient.Amount <= 0 {
+			log.Panicf("ERROR: Amount for '%s' must be positive", recipient.Address)
+		}
+
+		outputs = append(outputs, *NewTXOutput(recipient.Amount, recipient.Address))
+	}
+
+	bc := NewBlockchain()
+	UTXOSet := UTXOSet{bc}
+	defer bc.db.Close()
+
+	tx := &Transaction{nil, inputs, outputs, lockTime, nil}
+
+	// Pick inputs from the address and send the change back to it
+	if from != "" {
+		if !ValidateAddress(from) {
+			log.Panic("ERROR: Sender address is not valid")
+		}
+
+		hash := Base58Decode([]byte(from))
+		hash = hash[1 : len(hash)-addressChecksumLen]
+
+		var err error
+		tx, err = NewFundedTransaction(UTXOSet.FindSpendableUTXOs(hash), recipients, from, LargestFirstSelector{}, fee)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		tx.LockTime = lockTime
+		for index := range tx.Vin {
+			tx.Vin[index].Sequence = sequence
+		}
+	}
+
+	ptx := NewPartialTransaction(tx, bc.findPrevTransactions(tx))
+	ptx.SaveToFile(file)
+
+	fmt.Printf("Partially signed transaction with %d inputs written to %s\n", len(ptx.Inputs), file)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// outpointsFlag collects repeated -in TXID:VOUT[:SEQUENCE] flags
+type outpointsFlag []TXInput
+
+func (o *outpointsFlag) String() string {
+	pairs := []string{}
+
+	for _, input := range *o {
+		pair := fmt.Sprintf("%x:%d", input.Txid, input.Vout)
+		if input.Sequence != 0 {
+			pair += fmt.Sprintf(":%d", input.Sequence)
+		}
+		pairs = append(pairs, pair)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (o *outpointsFlag) Set(value string) error {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return fmt.Errorf("expected TXID:VOUT[:SEQUENCE], got '%s'", value)
+	}
+
+	txid, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return err
+	}
+
+	vout, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+
+	sequence := 0
+	if len(parts) == 3 {
+		sequence, err = strconv.Atoi(parts[2])
+		if err != nil {
+			return err
+		}
+	}
+
+	*o = append(*o, TXInput{txid, vout, nil, sequence})
+	return nil
+}
+
+func (cli *CLI) createRawTransaction(inputs []TXInput, recipients []Recipient, lockTime int) {
+	outputs := []TXOutput{}
+
+	for _, recipient := range recipients {
+		if !ValidateAddress(recipient.Address) {
+			log.Panicf("ERROR: Recipient address '%s' is not valid", recipient.Address)
+		}
+		if recipient.Amount <= 0 {
+			log.Panicf("ERROR: Amount for '%s' must be positive", recipient.Address)
+		}
+
+		outputs = append(outputs, *NewTXOutput(recipient.Amount, recipient.Address))
+	}
+
+	tx := Transaction{nil, inputs, outputs, lockTime, nil}
+	tx.ID = tx.Hash()
+
+	fmt.Println(hex.EncodeToString(tx.Serialize()))
+}
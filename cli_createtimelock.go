@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) createTimelock(address string, lockTime int, relative bool) {
+	if !ValidateAddress(address) {
+		log.Panic("ERROR: Address is not valid")
+	}
+	if lockTime <= 0 || (relative && lockTime >= lockTimeThreshold) {
+		log.Panic("ERROR: Lock time must be positive, and a number of blocks when relative")
+	}
+
+	pubKeyHash := AddressScript(address).PubKeyHash()
+	if pubKeyHash == nil {
+		log.Panic("ERROR: Timelocks can only pay to a key address")
+	}
+
+	wallets, _ := NewWallets()
+	timelockAddress := wallets.AddTimelock(lockTime, relative, pubKeyHash)
+	wallets.SaveToFile()
+
+	fmt.Printf("Your new timelocked address: %s\n", timelockAddress)
+	fmt.Printf("Redeem script: %x\n", []byte(wallets.Scripts[timelockAddress]))
+}
@@ -1,11 +1,45 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) createWallet(keyTypeName string, mnemonic bool, words int, passphrase, label, account string) {
+	keyType, err := ParseKeyType(keyTypeName)
+	if err != nil {
+		log.Panic(err)
+	}
 
-func (cli *CLI) createWallet() {
 	wallets, _ := NewWallets()
-	address := wallets.CreateWallet()
+
+	phrase := ""
+	if mnemonic {
+		phrase, err = NewMnemonic(words)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		err = wallets.SetSeed(MnemonicSeed(phrase, passphrase))
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	address := wallets.CreateWallet(keyType)
+	err = wallets.SetLabel(address, label)
+	if err != nil {
+		log.Panic(err)
+	}
+	err = wallets.SetAccount(address, account)
+	if err != nil {
+		log.Panic(err)
+	}
 	wallets.SaveToFile()
 
+	if phrase != "" {
+		fmt.Printf("Your mnemonic: %s\n", phrase)
+		fmt.Println("Write it down, it restores every address of the wallet together with the passphrase")
+	}
 	fmt.Printf("Your new address: %s\n", address)
 }
@@ -0,0 +1,12 @@
+package main
+
+import "fmt"
+
+func (cli *CLI) decodePSBT(file string) {
+	ptx := LoadPartialTransaction(file)
+
+	fmt.Println(ptx.Tx)
+	for index, input := range ptx.Inputs {
+		fmt.Printf("Input %d spends %d locked with '%s', signed: %t\n", index, input.PrevOutput.Value, input.PrevOutput.ScriptPubKey, ptx.IsInputSigned(index))
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+// Decodes a hex encoded transaction
+func decodeRawTransaction(rawHex string) Transaction {
+	data, err := hex.DecodeString(rawHex)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return DeserializeTransaction(data)
+}
+
+func (cli *CLI) decodeRawTransaction(rawHex string) {
+	tx := decodeRawTransaction(rawHex)
+
+	fmt.Println(tx)
+}
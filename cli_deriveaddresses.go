@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) deriveAddresses(xpub string, start, count int) {
+	account, err := ParseExtendedKey(xpub)
+	if err != nil {
+		log.Panic(err)
+	}
+	if start < 0 || count < 0 || uint64(start)+uint64(count) > uint64(HardenedKeyStart) {
+		log.Panic("ERROR: Addresses must have non-hardened indexes")
+	}
+
+	for index := start; index < start+count; index++ {
+		key, err := account.Child(uint32(index))
+		if err == errInvalidChild {
+			continue
+		}
+		if err != nil {
+			log.Panic(err)
+		}
+
+		fmt.Printf("%d %s\n", index, encodeAddress(version, HashPubKey(key.PubKey())))
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) dumpPrivKey(address string) {
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if _, ok := wallets.Wallets[address]; !ok {
+		log.Panicf("ERROR: Address '%s' is not in the wallet", address)
+	}
+
+	wif, err := wallets.GetWallet(address).WIF()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Println(wif)
+}
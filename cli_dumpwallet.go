@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+func (cli *CLI) dumpWallet(file string) {
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	dump := bytes.Buffer{}
+	err = wallets.Dump(&dump)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = ioutil.WriteFile(file, dump.Bytes(), 0600)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("Wallet dumped to %s, keep it secret\n", file)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) encryptWallet(passphrase string) {
+	wallets, _ := NewWallets()
+	err := wallets.Encrypt(passphrase)
+	if err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveToFile()
+	removeUnlockSession()
+
+	fmt.Println("Wallet encrypted, unlock it with walletpassphrase to sign transactions")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) extractPreimage(txidHex string) {
+	txid, err := hex.DecodeString(txidHex)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	bc := NewBlockchain()
+	mempool := Mempool{bc}
+	defer bc.db.Close()
+
+	// Look in the pool as well, so the secret is known before it is mined
+	tx, err := bc.FindTransaction(txid)
+	if err != nil {
+		for _, pooled := range mempool.Transactions() {
+			if bytes.Equal(pooled.ID, txid) {
+				tx, err = *pooled, nil
+			}
+		}
+	}
+	if err != nil {
+		log.Panic(err)
+	}
+
+	preimage, err := ExtractPreimage(&tx)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("%x\n", preimage)
+}
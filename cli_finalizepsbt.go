@@ -0,0 +1,18 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) finalizePSBT(file string) {
+	ptx := LoadPartialTransaction(file)
+
+	tx, err := ptx.Finalize()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Println(hex.EncodeToString(tx.Serialize()))
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+)
+
+func (cli *CLI) findData(prefixHex string) {
+	prefix, err := hex.DecodeString(prefixHex)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	bc := NewBlockchain()
+	defer bc.db.Close()
+
+	for _, output := range bc.FindDataOutputs(prefix) {
+		timestamp := time.Unix(output.Timestamp, 0).UTC().Format(time.RFC3339)
+		fmt.Printf("Block %d (%s) %x:%d %x\n", output.Height, timestamp, output.Txid, output.Vout, output.Data)
+	}
+}
@@ -6,6 +6,11 @@ import (
 )
 
 func (cli *CLI) getBalance(address string) {
+	if address == "" {
+		cli.getWalletBalance()
+		return
+	}
+
 	if !ValidateAddress(address) {
 		log.Panic("ERROR: Address is not valid")
 	}
@@ -13,14 +18,76 @@ func (cli *CLI) getBalance(address string) {
 	UTXOSet := UTXOSet{bc}
 	defer bc.db.Close()
 
-	balance := 0
 	pubKeyHash := Base58Decode([]byte(address))
 	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-4]
-	UTXOs := UTXOSet.FindUTXO(pubKeyHash)
+	spendable, immature := UTXOSet.GetBalance(pubKeyHash)
+
+	fmt.Printf("Balance of '%s': %d\n", address, spendable+immature)
+	fmt.Printf("  Spendable: %d\n", spendable)
+	fmt.Printf("  Immature: %d\n", immature)
+}
+
+// Prints the balance of every address of the wallet, the watch-only ones
+// apart
+func (cli *CLI) getWalletBalance() {
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+	addresses := wallets.AllAddresses()
+
+	bc := NewBlockchain()
+	defer bc.db.Close()
+
+	total, watchOnly := splitWatchOnly(addresses, addressBalances(bc, sortedKeys(addresses)))
+
+	fmt.Printf("Balance of the wallet: %d\n", total.spendable+total.immature)
+	fmt.Printf("  Spendable: %d\n", total.spendable)
+	fmt.Printf("  Immature: %d\n", total.immature)
+	fmt.Printf("Watch-only balance: %d\n", watchOnly.spendable+watchOnly.immature)
+}
+
+// balance is the spendable and the immature amounts of some addresses
+type balance struct {
+	spendable int
+	immature  int
+}
+
+func (b balance) add(other balance) balance {
+	return balance{b.spendable + other.spendable, b.immature + other.immature}
+}
+
+// Returns the total balance of the addresses the wallet has keys or
+// scripts for, and the one of the watch-only addresses
+func splitWatchOnly(addresses map[string]bool, balances map[string]balance) (balance, balance) {
+	total, watchOnly := balance{}, balance{}
+	for address, addressBalance := range balances {
+		if addresses[address] {
+			watchOnly = watchOnly.add(addressBalance)
+		} else {
+			total = total.add(addressBalance)
+		}
+	}
+
+	return total, watchOnly
+}
+
+// Returns the balances of the addresses, from the UTXO set
+func addressBalances(bc *Blockchain, addresses []string) map[string]balance {
+	balances := make(map[string]balance)
+	for _, address := range addresses {
+		balances[address] = balance{}
+	}
 
-	for _, out := range UTXOs {
-		balance += out.Value
+	for _, utxo := range (UTXOSet{bc}).FindAddressUTXOs(addresses) {
+		addressBalance := balances[utxo.Address]
+		if utxo.Mature {
+			addressBalance.spendable += utxo.Output.Value
+		} else {
+			addressBalance.immature += utxo.Output.Value
+		}
+		balances[utxo.Address] = addressBalance
 	}
 
-	fmt.Printf("Balance of '%s': %d\n", address, balance)
+	return balances
 }
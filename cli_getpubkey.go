@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) getPubKey(address string, uncompressed bool) {
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if _, ok := wallets.Wallets[address]; !ok {
+		log.Panicf("ERROR: Address '%s' is not in the wallet", address)
+	}
+
+	wallet := wallets.GetWallet(address)
+	if uncompressed {
+		fmt.Printf("%x\n", encodeUncompressedPubKey(wallet.PrivateKey.PublicKey))
+		return
+	}
+
+	fmt.Printf("%x\n", wallet.PublicKey)
+}
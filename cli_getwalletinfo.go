@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) getWalletInfo() {
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	addresses := wallets.AllAddresses()
+	fmt.Printf("Addresses: %d (%d keys, %d scripts, %d watch-only)\n", len(addresses), len(wallets.Wallets), len(wallets.Scripts), len(wallets.Watched))
+	for keyType := KeyTypeP256; keyType.IsValid(); keyType++ {
+		fmt.Printf("  Derived %s keys: %d\n", keyType, wallets.NextIndex[keyType])
+	}
+
+	switch {
+	case wallets.IsLocked():
+		fmt.Println("Encryption: locked")
+	case wallets.IsEncrypted():
+		fmt.Println("Encryption: unlocked")
+	default:
+		fmt.Println("Encryption: none")
+	}
+
+	if !dbExists() {
+		return
+	}
+
+	bc := NewBlockchain()
+	balances := addressBalances(bc, sortedKeys(addresses))
+	bc.db.Close()
+
+	total, watchOnly := splitWatchOnly(addresses, balances)
+
+	fmt.Printf("Balance: %d\n", total.spendable+total.immature)
+	fmt.Printf("  Spendable: %d\n", total.spendable)
+	fmt.Printf("  Immature: %d\n", total.immature)
+	fmt.Printf("Watch-only balance: %d\n", watchOnly.spendable+watchOnly.immature)
+
+	fmt.Println("Accounts:")
+	for _, group := range wallets.AccountGroups() {
+		accountBalance := balance{}
+		for _, address := range group.Addresses {
+			accountBalance = accountBalance.add(balances[address])
+		}
+		fmt.Printf("  %s: %d\n", group.Name, accountBalance.spendable+accountBalance.immature)
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) getXPub(keyTypeName string) {
+	keyType, err := ParseKeyType(keyTypeName)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallets, _ := NewWallets()
+	account, err := wallets.AccountKey(keyType)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Println(account.Neuter())
+}
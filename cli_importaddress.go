@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) importAddress(address, pubKeyHex string, rescan bool) {
+	var pubKey []byte
+	if pubKeyHex != "" {
+		var err error
+		pubKey, err = hex.DecodeString(pubKeyHex)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	wallets, _ := NewWallets()
+	address, err := wallets.AddWatchOnly(address, pubKey)
+	if err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveToFile()
+
+	fmt.Printf("Watching address: %s\n", address)
+
+	if rescan {
+		cli.rescanAddresses([]string{address})
+	}
+}
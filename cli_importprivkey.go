@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) importPrivKey(wif string, rescan bool) {
+	privKey, err := DecodeWIF(wif)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallets, _ := NewWallets()
+	address, err := wallets.ImportKey(privKey)
+	if err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveToFile()
+
+	fmt.Printf("Imported address: %s\n", address)
+
+	if rescan {
+		cli.rescanAddresses([]string{address})
+	}
+}
+
+// Scans the UTXO set for the outputs of newly imported addresses and prints
+// their balances. Nothing is scanned without a blockchain
+func (cli *CLI) rescanAddresses(addresses []string) {
+	if !dbExists() {
+		return
+	}
+
+	bc := NewBlockchain()
+	UTXOSet := UTXOSet{bc}
+	defer bc.db.Close()
+
+	for _, address := range addresses {
+		pubKeyHash := Base58Decode([]byte(address))
+		pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-addressChecksumLen]
+		spendable, immature := UTXOSet.GetBalance(pubKeyHash)
+
+		fmt.Printf("Balance of '%s': %d\n", address, spendable+immature)
+	}
+}
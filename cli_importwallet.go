@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+func (cli *CLI) importWallet(file string, rescan bool) {
+	dump, err := os.Open(file)
+	if err != nil {
+		log.Panic(err)
+	}
+	defer dump.Close()
+
+	wallets, _ := NewWallets()
+	addresses, err := wallets.ImportDump(dump)
+	if err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveToFile()
+
+	fmt.Printf("Imported %d keys\n", len(addresses))
+
+	if rescan {
+		cli.rescanAddresses(addresses)
+	}
+}
@@ -3,16 +3,76 @@ package main
 import (
 	"fmt"
 	"log"
+	"time"
 )
 
-func (cli *CLI) listAddresses() {
+func (cli *CLI) listAddresses(account string) {
 	wallets, err := NewWallets()
 	if err != nil {
 		log.Panic(err)
 	}
-	addresses := wallets.GetAddresses()
 
-	for _, address := range addresses {
-		fmt.Println(address)
+	var balances map[string]balance
+	if dbExists() {
+		bc := NewBlockchain()
+		balances = addressBalances(bc, sortedKeys(wallets.AllAddresses()))
+		bc.db.Close()
 	}
+
+	for _, group := range wallets.AccountGroups() {
+		if account != "" && group.Name != account {
+			continue
+		}
+
+		fmt.Printf("Account %s:\n", group.Name)
+		for _, address := range group.Addresses {
+			fmt.Printf("  %s%s\n", address, describeAddress(wallets, address, balances))
+		}
+	}
+}
+
+// Returns the balance, the label and what kind of address it is
+func describeAddress(wallets *Wallets, address string, balances map[string]balance) string {
+	description := ""
+	if balances != nil {
+		addressBalance := balances[address]
+		description += fmt.Sprintf(" %d", addressBalance.spendable+addressBalance.immature)
+	}
+	if label, ok := wallets.Labels[address]; ok {
+		description += fmt.Sprintf(" %q", label)
+	}
+
+	if redeemScript, ok := wallets.Scripts[address]; ok {
+		description += fmt.Sprintf(" (%s)", describeRedeemScript(redeemScript))
+	}
+	if _, ok := wallets.Watched[address]; ok {
+		description += " (watch-only)"
+	}
+
+	return description
+}
+
+// Returns a short description of what a redeem script requires
+func describeRedeemScript(redeemScript Script) string {
+	if lockTime, relative, _ := redeemScript.Timelock(); lockTime > 0 {
+		if relative {
+			return fmt.Sprintf("locked for %d blocks", lockTime)
+		}
+		return fmt.Sprintf("locked until %s", describeLockTime(lockTime))
+	}
+
+	if h := ParseHTLC(redeemScript); h != nil {
+		return fmt.Sprintf("HTLC refundable from %s", describeLockTime(h.Timeout))
+	}
+
+	m, pubKeys := redeemScript.MultisigKeys()
+	return fmt.Sprintf("%d-of-%d multisig", m, len(pubKeys))
+}
+
+func describeLockTime(lockTime int) string {
+	if lockTime < lockTimeThreshold {
+		return fmt.Sprintf("block %d", lockTime)
+	}
+
+	return time.Unix(int64(lockTime), 0).UTC().Format(time.RFC3339)
 }
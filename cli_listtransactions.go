@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) listTransactions(address string) {
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+	addresses := wallets.AllAddresses()
+	if address != "" {
+		if _, ok := addresses[address]; !ok {
+			log.Panicf("ERROR: Address '%s' is not in the wallet", address)
+		}
+		addresses = map[string]bool{address: addresses[address]}
+	}
+
+	bc := NewBlockchain()
+	defer bc.db.Close()
+
+	for _, entry := range bc.FindHistory(sortedKeys(addresses)) {
+		watchOnly := ""
+		for entryAddress := range entry.Addresses {
+			if addresses[entryAddress] {
+				watchOnly = " (watch-only)"
+			}
+		}
+
+		fmt.Printf("Block %d %x: received %d, sent %d%s\n", entry.Height, entry.TxID, entry.Received, entry.Sent, watchOnly)
+	}
+}
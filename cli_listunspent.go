@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+)
+
+func (cli *CLI) listUnspent(address string) {
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+	addresses := wallets.AllAddresses()
+	if address != "" {
+		if _, ok := addresses[address]; !ok {
+			log.Panicf("ERROR: Address '%s' is not in the wallet", address)
+		}
+		addresses = map[string]bool{address: addresses[address]}
+	}
+
+	bc := NewBlockchain()
+	UTXOSet := UTXOSet{bc}
+	defer bc.db.Close()
+
+	UTXOs := UTXOSet.FindAddressUTXOs(sortedKeys(addresses))
+	sort.Slice(UTXOs, func(i, j int) bool {
+		if UTXOs[i].Address != UTXOs[j].Address {
+			return UTXOs[i].Address < UTXOs[j].Address
+		}
+		if c := bytes.Compare(UTXOs[i].Txid, UTXOs[j].Txid); c != 0 {
+			return c < 0
+		}
+		return UTXOs[i].Vout < UTXOs[j].Vout
+	})
+
+	for _, utxo := range UTXOs {
+		fmt.Printf("%x:%d %s %d%s\n", utxo.Txid, utxo.Vout, utxo.Address, utxo.Output.Value, describeUTXO(utxo, addresses[utxo.Address]))
+	}
+}
+
+// Returns the flags of an unspent output, if any
+func describeUTXO(utxo AddressUTXO, watchOnly bool) string {
+	flags := ""
+	if !utxo.Mature {
+		flags += " (immature)"
+	}
+	if watchOnly {
+		flags += " (watch-only)"
+	}
+
+	return flags
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) mine(address string) {
+	if !ValidateAddress(address) {
+		log.Panic("ERROR: Address is not valid")
+	}
+
+	bc := NewBlockchain()
+	UTXOSet := UTXOSet{bc}
+	mempool := Mempool{bc}
+	defer bc.db.Close()
+
+	cbTx := NewCoinbaseTX(address, "")
+	transactions := append([]*Transaction{cbTx}, mempool.Transactions()...)
+
+	newBlock := bc.MineBlock(transactions)
+	UTXOSet.Update(newBlock)
+	mempool.Update(newBlock)
+
+	fmt.Printf("Mined block %d with %d transactions\n", newBlock.Height, len(newBlock.Transactions))
+}
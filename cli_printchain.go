@@ -17,7 +17,8 @@ func (cli *CLI) printChain() {
 		fmt.Printf("============ Block %x ============\n", block.Hash)
 		fmt.Printf("Prev. block: %x\n", block.PrevBlockHash)
 		pow := NewProofOfWork(block)
-		fmt.Printf("PoW: %s\n\n", strconv.FormatBool(pow.Validate()))
+		fmt.Printf("PoW: %s\n", strconv.FormatBool(pow.Validate()))
+		fmt.Printf("Witness commitment: %s\n\n", strconv.FormatBool(block.HasValidWitnessCommitment()))
 		for _, tx := range block.Transactions {
 			fmt.Println(tx)
 		}
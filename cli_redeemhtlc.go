@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) redeemHTLC(address, preimageHex, to string, fee int) {
+	preimage, err := hex.DecodeString(preimageHex)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	cli.spendHTLC(address, preimage, to, fee)
+}
+
+// Sends the outputs of an HTLC address of the wallet to the pool
+func (cli *CLI) spendHTLC(address string, preimage []byte, to string, fee int) {
+	if !ValidateAddress(to) {
+		log.Panic("ERROR: Recipient address is not valid")
+	}
+
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	h := ParseHTLC(wallets.Scripts[address])
+	if h == nil {
+		log.Panicf("ERROR: Address '%s' is not an HTLC of the wallet", address)
+	}
+
+	bc := NewBlockchain()
+	UTXOSet := UTXOSet{bc}
+	mempool := Mempool{bc}
+	defer bc.db.Close()
+
+	tx, err := NewHTLCTransaction(wallets, *h, preimage, to, fee, &UTXOSet)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = mempool.Add(tx)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	fmt.Printf("Transaction %x added to the pool\n", tx.ID)
+}
@@ -0,0 +1,5 @@
+package main
+
+func (cli *CLI) refundHTLC(address, to string, fee int) {
+	cli.spendHTLC(address, nil, to, fee)
+}
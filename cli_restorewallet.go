@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+func (cli *CLI) restoreWallet(mnemonic, passphrase string, gapLimit int) {
+	if _, err := os.Stat(walletFile); err == nil {
+		log.Panic("ERROR: A wallet file already exists, move it away to restore another wallet")
+	}
+
+	err := ValidateMnemonic(mnemonic)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallets := newWallets()
+	err = wallets.SetSeed(MnemonicSeed(mnemonic, passphrase))
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if dbExists() {
+		bc := NewBlockchain()
+		found, err := wallets.Rescan(bc.FindUsedPubKeyHashes(), gapLimit)
+		bc.db.Close()
+		if err != nil {
+			log.Panic(err)
+		}
+
+		fmt.Printf("Found %d used addresses\n", found)
+	}
+
+	if len(wallets.Wallets) == 0 {
+		wallets.CreateWallet(KeyTypeP256)
+	}
+	wallets.SaveToFile()
+
+	for _, address := range wallets.GetAddresses() {
+		fmt.Println(address)
+	}
+}
@@ -1,28 +1,5 @@
 package main
 
-import (
-	"fmt"
-	"log"
-)
-
-func (cli *CLI) send(from, to string, amount int) {
-	if !ValidateAddress(from) {
-		log.Panic("ERROR: Sender address is not valid")
-	}
-	if !ValidateAddress(to) {
-		log.Panic("ERROR: Recipient address is not valid")
-	}
-
-	bc := NewBlockchain()
-	UTXOSet := UTXOSet{bc}
-	defer bc.db.Close()
-
-	tx := NewUTXOTransaction(from, to, amount, &UTXOSet)
-	cbTx := NewCoinbaseTX(from, "")
-	transactions := []*Transaction{cbTx, tx}
-
-	newBlock := bc.MineBlock(transactions)
-	UTXOSet.Update(newBlock)
-
-	fmt.Println("Success!")
+func (cli *CLI) send(from []string, change, to string, amount int, strategy string, fee int) {
+	cli.sendMany(from, change, []Recipient{{to, amount}}, strategy, fee)
 }
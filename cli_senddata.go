@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) sendData(from, dataHex string, fee int) {
+	if !ValidateAddress(from) {
+		log.Panic("ERROR: Sender address is not valid")
+	}
+	if fee < 0 {
+		log.Panic("ERROR: Fee can't be negative")
+	}
+
+	data, err := hex.DecodeString(dataHex)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	bc := NewBlockchain()
+	UTXOSet := UTXOSet{bc}
+	mempool := Mempool{bc}
+	defer bc.db.Close()
+
+	tx, err := NewDataTransaction(wallets, from, data, fee, &UTXOSet)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	cbTx := NewCoinbaseTX(from, "")
+	newBlock := bc.MineBlock([]*Transaction{cbTx, tx})
+	UTXOSet.Update(newBlock)
+	mempool.Update(newBlock)
+
+	fmt.Printf("Data stored in transaction %x, block %d\n", tx.ID, newBlock.Height)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// recipientsFlag collects repeated -to ADDRESS:AMOUNT flags
+type recipientsFlag []Recipient
+
+func (r *recipientsFlag) String() string {
+	pairs := []string{}
+
+	for _, recipient := range *r {
+		pairs = append(pairs, fmt.Sprintf("%s:%d", recipient.Address, recipient.Amount))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (r *recipientsFlag) Set(value string) error {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return fmt.Errorf("expected ADDRESS:AMOUNT, got '%s'", value)
+	}
+
+	amount, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+
+	*r = append(*r, Recipient{parts[0], amount})
+	return nil
+}
+
+// Reads recipients from a JSON file holding a list of
+// {"address": ADDRESS, "amount": AMOUNT} objects
+func loadRecipients(file string) []Recipient {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	recipients := []Recipient{}
+	err = json.Unmarshal(content, &recipients)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return recipients
+}
+
+// Checks that there are recipients, each with a valid address of its own
+// and a positive amount
+func validateRecipients(recipients []Recipient) error {
+	if len(recipients) == 0 {
+		return errors.New("No recipients to pay")
+	}
+
+	seen := make(map[string]bool)
+	for _, recipient := range recipients {
+		if !ValidateAddress(recipient.Address) {
+			return fmt.Errorf("Recipient address '%s' is not valid", recipient.Address)
+		}
+		if recipient.Amount <= 0 {
+			return fmt.Errorf("Amount for '%s' must be positive", recipient.Address)
+		}
+		if seen[recipient.Address] {
+			return fmt.Errorf("Recipient '%s' is listed more than once", recipient.Address)
+		}
+		seen[recipient.Address] = true
+	}
+
+	return nil
+}
+
+func (cli *CLI) sendMany(from []string, change string, recipients []Recipient, strategy string, fee int) {
+	for _, address := range from {
+		if !ValidateAddress(address) {
+			log.Panicf("ERROR: Sender address '%s' is not valid", address)
+		}
+	}
+	if change != "" && !ValidateAddress(change) {
+		log.Panic("ERROR: Change address is not valid")
+	}
+	err := validateRecipients(recipients)
+	if err != nil {
+		log.Panicf("ERROR: %s", err)
+	}
+
+	if fee < 0 {
+		log.Panic("ERROR: Fee can't be negative")
+	}
+
+	selector, err := NewCoinSelector(strategy)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+	from, change = wallets.FundingAddresses(from, change)
+
+	bc := NewBlockchain()
+	UTXOSet := UTXOSet{bc}
+	mempool := Mempool{bc}
+	defer bc.db.Close()
+
+	tx := NewWalletTransaction(wallets, from, change, recipients, selector, fee, &UTXOSet)
+	cbTx := NewCoinbaseTX(change, "")
+	transactions := []*Transaction{cbTx, tx}
+
+	newBlock := bc.MineBlock(transactions)
+	UTXOSet.Update(newBlock)
+	mempool.Update(newBlock)
+
+	if len(recipients) == 1 {
+		fmt.Println("Success!")
+	} else {
+		fmt.Printf("Success! Paid %d recipients\n", len(recipients))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipientsFlag(t *testing.T) {
+	address := string(NewWallet(KeyTypeP256).GetAddress())
+
+	for _, value := range []string{
+		address + "=5",
+		address,
+		address + ":",
+		address + ":five",
+		address + ":5:6",
+	} {
+		recipients := recipientsFlag{}
+		assert.NotNil(t, recipients.Set(value), "Value '%s'", value)
+		assert.Empty(t, recipients)
+	}
+
+	recipients := recipientsFlag{}
+	assert.Nil(t, recipients.Set(address+":5"))
+	assert.Nil(t, recipients.Set(address+":-1"))
+	assert.Equal(t, recipientsFlag{{address, 5}, {address, -1}}, recipients)
+	assert.Equal(t, address+":5,"+address+":-1", recipients.String())
+}
+
+func TestValidateRecipients(t *testing.T) {
+	a := string(NewWallet(KeyTypeP256).GetAddress())
+	b := string(NewWallet(KeyTypeSecp256k1).GetAddress())
+
+	for _, test := range []struct {
+		name       string
+		recipients []Recipient
+		valid      bool
+	}{
+		{"Empty list", []Recipient{}, false},
+		{"Duplicate address", []Recipient{{a, 1}, {b, 2}, {a, 3}}, false},
+		{"Zero amount", []Recipient{{a, 0}}, false},
+		{"Negative amount", []Recipient{{a, 1}, {b, -1}}, false},
+		{"Invalid address", []Recipient{{"1abc", 1}}, false},
+		{"Distinct recipients", []Recipient{{a, 1}, {b, 2}}, true},
+	} {
+		err := validateRecipients(test.recipients)
+		assert.Equal(t, test.valid, err == nil, test.name)
+	}
+}
+
+func TestLoadRecipients(t *testing.T) {
+	chdirTemp(t)
+	address := string(NewWallet(KeyTypeP256).GetAddress())
+
+	assert.Nil(t, ioutil.WriteFile("recipients.json", []byte(`[{"address": "`+address+`", "amount": 5}]`), 0600))
+	assert.Equal(t, []Recipient{{address, 5}}, loadRecipients("recipients.json"))
+
+	assert.Nil(t, ioutil.WriteFile("recipients.json", []byte(`[]`), 0600))
+	assert.NotNil(t, validateRecipients(loadRecipients("recipients.json")))
+
+	assert.Nil(t, ioutil.WriteFile("recipients.json", []byte(address+":5"), 0600))
+	assert.Panics(t, func() { loadRecipients("recipients.json") })
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) sendRawTransaction(rawHex string, mine bool, minerAddress string) {
+	tx := decodeRawTransaction(rawHex)
+
+	if mine && !ValidateAddress(minerAddress) {
+		log.Panic("ERROR: Miner address is not valid")
+	}
+
+	bc := NewBlockchain()
+	mempool := Mempool{bc}
+	defer bc.db.Close()
+
+	err := mempool.Add(&tx)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if !mine {
+		fmt.Printf("Transaction %x added to the pool\n", tx.ID)
+		return
+	}
+
+	UTXOSet := UTXOSet{bc}
+	cbTx := NewCoinbaseTX(minerAddress, "")
+	transactions := append([]*Transaction{cbTx}, mempool.Transactions()...)
+
+	newBlock := bc.MineBlock(transactions)
+	UTXOSet.Update(newBlock)
+	mempool.Update(newBlock)
+
+	fmt.Printf("Transaction %x mined in block %d\n", tx.ID, newBlock.Height)
+}
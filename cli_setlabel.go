@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) setLabel(address, label string) {
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = wallets.SetLabel(address, label)
+	if err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveToFile()
+
+	if label == "" {
+		fmt.Printf("Label of '%s' removed\n", address)
+		return
+	}
+	fmt.Printf("Label of '%s' set to %q\n", address, label)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// Signing a partially signed transaction only needs the wallet file,
+// so it can be done on a machine without the chain
+func (cli *CLI) signPSBT(file, out, sigHash, sigTypeName string) {
+	hashType, err := ParseSigHashType(sigHash)
+	if err != nil {
+		log.Panic(err)
+	}
+	sigType, err := ParseSigType(sigTypeName)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	ptx := LoadPartialTransaction(file)
+	signed, err := ptx.Sign(wallets, hashType, sigType)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	if out == "" {
+		out = file
+	}
+	ptx.SaveToFile(out)
+
+	complete := 0
+	for index := range ptx.Inputs {
+		if ptx.IsInputSigned(index) {
+			complete++
+		}
+	}
+
+	fmt.Printf("Added %d signatures, %d of %d inputs fully signed, written to %s\n", signed, complete, len(ptx.Inputs), out)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) signRawTransaction(rawHex, sigHash, sigTypeName string) {
+	hashType, err := ParseSigHashType(sigHash)
+	if err != nil {
+		log.Panic(err)
+	}
+	sigType, err := ParseSigType(sigTypeName)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	tx := decodeRawTransaction(rawHex)
+
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	bc := NewBlockchain()
+	defer bc.db.Close()
+
+	prevTXs := bc.findPrevTransactions(&tx)
+	hashes := NewSigHashes(&tx)
+	signed := 0
+
+	for index, vin := range tx.Vin {
+		prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
+		if vin.Vout < 0 || vin.Vout >= len(prevTx.Vout) {
+			log.Panicf("ERROR: Input %d references a missing output", index)
+		}
+
+		wallet := wallets.GetWalletByPubKeyHash(prevTx.Vout[vin.Vout].ScriptPubKey.PubKeyHash())
+		if wallet == nil {
+			continue
+		}
+
+		signer, err := wallet.SignerOfType(sigType)
+		if err != nil {
+			log.Panicf("ERROR: Can't sign input %d: %s", index, err)
+		}
+
+		tx.SignInput(hashes, index, signer, prevTXs, hashType)
+		signed++
+	}
+
+	tx.ID = tx.Hash()
+
+	fmt.Println(hex.EncodeToString(tx.Serialize()))
+	fmt.Printf("Signed %d of %d inputs\n", signed, len(tx.Vin))
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func (cli *CLI) walletLock() {
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+	if !wallets.IsEncrypted() {
+		log.Panic("ERROR: The wallet isn't encrypted")
+	}
+	removeUnlockSession()
+
+	fmt.Println("Wallet locked")
+}
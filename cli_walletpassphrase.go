@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+func (cli *CLI) walletPassphrase(passphrase string, timeout int) {
+	if timeout <= 0 {
+		log.Panic("ERROR: Timeout must be positive")
+	}
+
+	wallets, err := NewWallets()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	key, err := wallets.Unlock(passphrase)
+	if err != nil {
+		log.Panic(err)
+	}
+	saveUnlockSession(key, time.Now().Add(time.Duration(timeout)*time.Second))
+
+	fmt.Printf("Wallet unlocked for %d seconds\n", timeout)
+	fmt.Printf("Its key is kept unencrypted in %s until walletlock, or the first command run after the timeout, removes it\n", walletUnlockFile)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Maximum number of nodes visited by the branch and bound search
+const bnbMaxTries = 100000
+
+var errNotEnoughFunds = errors.New("Not enough funds")
+
+// UTXO is an unspent output together with the outpoint referencing it
+type UTXO struct {
+	Txid   []byte
+	Vout   int
+	Output TXOutput
+}
+
+// CoinSelector picks the outputs used to fund a transaction. The selected
+// outputs have to cover amount plus feePerInput for every selected output
+type CoinSelector interface {
+	Select(utxos []UTXO, amount, feePerInput int) ([]UTXO, error)
+}
+
+// NewCoinSelector returns the coin selector registered under name
+func NewCoinSelector(name string) (CoinSelector, error) {
+	switch name {
+	case "largest":
+		return LargestFirstSelector{}, nil
+	case "smallest":
+		return SmallestFirstSelector{}, nil
+	case "bnb":
+		return BranchAndBoundSelector{LargestFirstSelector{}}, nil
+	case "random":
+		return RandomImproveSelector{}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown coin selection strategy '%s'", name)
+}
+
+// LargestFirstSelector spends the biggest outputs first, which keeps the
+// number of inputs low
+type LargestFirstSelector struct{}
+
+// Select implements CoinSelector
+func (s LargestFirstSelector) Select(utxos []UTXO, amount, feePerInput int) ([]UTXO, error) {
+	candidates := effectiveUTXOs(utxos, feePerInput)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Output.Value > candidates[j].Output.Value
+	})
+
+	return accumulate(candidates, amount, feePerInput)
+}
+
+// SmallestFirstSelector spends the smallest outputs first, which
+// consolidates dust at the price of more inputs
+type SmallestFirstSelector struct{}
+
+// Select implements CoinSelector
+func (s SmallestFirstSelector) Select(utxos []UTXO, amount, feePerInput int) ([]UTXO, error) {
+	candidates := effectiveUTXOs(utxos, feePerInput)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Output.Value < candidates[j].Output.Value
+	})
+
+	return accumulate(candidates, amount, feePerInput)
+}
+
+// BranchAndBoundSelector searches for a set of outputs that pays the amount
+// exactly, so no change output is needed. An overshoot of up to feePerInput
+// is accepted since spending the change later would cost that much anyway.
+// When there is no such set the Fallback selector is used
+type BranchAndBoundSelector struct {
+	Fallback CoinSelector
+}
+
+// Select implements CoinSelector
+func (s BranchAndBoundSelector) Select(utxos []UTXO, amount, feePerInput int) ([]UTXO, error) {
+	candidates := effectiveUTXOs(utxos, feePerInput)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Output.Value > candidates[j].Output.Value
+	})
+
+	// remaining[i] is the effective value of candidates[i:]
+	remaining := make([]int, len(candidates)+1)
+	for i := len(candidates) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + candidates[i].Output.Value - feePerInput
+	}
+
+	selected := []UTXO{}
+	tries := 0
+
+	var search func(index, total int) bool
+	search = func(index, total int) bool {
+		tries++
+		if total >= amount {
+			return total <= amount+feePerInput
+		}
+		if index == len(candidates) || tries > bnbMaxTries || total+remaining[index] < amount {
+			return false
+		}
+
+		// Include the candidate first, then explore without it
+		selected = append(selected, candidates[index])
+		if search(index+1, total+candidates[index].Output.Value-feePerInput) {
+			return true
+		}
+		selected = selected[:len(selected)-1]
+
+		return search(index+1, total)
+	}
+
+	if search(0, 0) {
+		return selected, nil
+	}
+
+	if s.Fallback == nil {
+		return nil, errNotEnoughFunds
+	}
+
+	return s.Fallback.Select(utxos, amount, feePerInput)
+}
+
+// RandomImproveSelector picks random outputs until the amount is covered,
+// then keeps adding random outputs while they bring the change closer to
+// the amount itself. This leaves change outputs of a useful size instead
+// of dust
+type RandomImproveSelector struct{}
+
+// Select implements CoinSelector
+func (s RandomImproveSelector) Select(utxos []UTXO, amount, feePerInput int) ([]UTXO, error) {
+	candidates := effectiveUTXOs(utxos, feePerInput)
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	selected := []UTXO{}
+	total := 0
+	next := 0
+
+	for ; next < len(candidates) && total < amount; next++ {
+		selected = append(selected, candidates[next])
+		total += candidates[next].Output.Value - feePerInput
+	}
+
+	if total < amount {
+		return nil, errNotEnoughFunds
+	}
+
+	// Aim for change equal to the amount, never going above twice of it
+	ideal := 2 * amount
+	for ; next < len(candidates); next++ {
+		value := candidates[next].Output.Value - feePerInput
+		if total+value > 3*amount || abs(ideal-(total+value)) >= abs(ideal-total) {
+			continue
+		}
+
+		selected = append(selected, candidates[next])
+		total += value
+	}
+
+	return selected, nil
+}
+
+// Returns the outputs that are worth more than the fee to spend them
+// in a stable order
+func effectiveUTXOs(utxos []UTXO, feePerInput int) []UTXO {
+	candidates := []UTXO{}
+
+	for _, utxo := range utxos {
+		if utxo.Output.Value > feePerInput {
+			candidates = append(candidates, utxo)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if c := bytes.Compare(candidates[i].Txid, candidates[j].Txid); c != 0 {
+			return c < 0
+		}
+		return candidates[i].Vout < candidates[j].Vout
+	})
+
+	return candidates
+}
+
+// Takes candidates in order until the amount and the fees are covered
+func accumulate(candidates []UTXO, amount, feePerInput int) ([]UTXO, error) {
+	selected := []UTXO{}
+	total := 0
+
+	for _, utxo := range candidates {
+		if total >= amount {
+			break
+		}
+
+		selected = append(selected, utxo)
+		total += utxo.Output.Value - feePerInput
+	}
+
+	if total < amount {
+		return nil, errNotEnoughFunds
+	}
+
+	return selected, nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
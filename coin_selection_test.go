@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestUTXOs(values ...int) []UTXO {
+	utxos := []UTXO{}
+
+	for i, value := range values {
+		utxos = append(utxos, UTXO{[]byte{byte(i)}, 0, TXOutput{value, nil}})
+	}
+
+	return utxos
+}
+
+func sumValues(utxos []UTXO) int {
+	total := 0
+	for _, utxo := range utxos {
+		total += utxo.Output.Value
+	}
+	return total
+}
+
+func TestLargestFirstSelector(t *testing.T) {
+	selected, err := LargestFirstSelector{}.Select(newTestUTXOs(1, 5, 3, 8), 9, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(selected), "Two largest outputs are selected")
+	assert.Equal(t, 13, sumValues(selected))
+}
+
+func TestSmallestFirstSelector(t *testing.T) {
+	selected, err := SmallestFirstSelector{}.Select(newTestUTXOs(1, 5, 3, 8), 9, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(selected), "Three smallest outputs are selected")
+	assert.Equal(t, 9, sumValues(selected))
+}
+
+func TestSelectorPaysFeePerInput(t *testing.T) {
+	// The 1 output isn't worth spending with a fee of 1
+	selected, err := SmallestFirstSelector{}.Select(newTestUTXOs(1, 5, 3, 8), 9, 1)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(selected))
+	assert.True(t, sumValues(selected)-len(selected) >= 9, "Selection covers amount and fees")
+}
+
+func TestBranchAndBoundSelectorFindsExactMatch(t *testing.T) {
+	selector := BranchAndBoundSelector{LargestFirstSelector{}}
+	selected, err := selector.Select(newTestUTXOs(10, 7, 4, 2, 1), 13, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 13, sumValues(selected), "No change is needed")
+}
+
+func TestBranchAndBoundSelectorFallsBack(t *testing.T) {
+	selector := BranchAndBoundSelector{LargestFirstSelector{}}
+	selected, err := selector.Select(newTestUTXOs(10, 10), 5, 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 10, sumValues(selected))
+
+	_, err = BranchAndBoundSelector{}.Select(newTestUTXOs(10, 10), 5, 0)
+	assert.Equal(t, errNotEnoughFunds, err)
+}
+
+func TestRandomImproveSelector(t *testing.T) {
+	utxos := newTestUTXOs(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+	for i := 0; i < 20; i++ {
+		selected, err := RandomImproveSelector{}.Select(utxos, 10, 0)
+
+		assert.Nil(t, err)
+		assert.True(t, sumValues(selected) >= 10, "Selection covers amount")
+	}
+
+	_, err := RandomImproveSelector{}.Select(utxos, 100, 0)
+	assert.Equal(t, errNotEnoughFunds, err)
+}
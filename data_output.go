@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Maximum number of bytes carried by a data output
+const maxDataCarrierSize = 80
+
+// DataOutput is a data carrier output found in the blockchain
+type DataOutput struct {
+	Txid      []byte
+	Vout      int
+	Height    int
+	Timestamp int64
+	Data      []byte
+}
+
+// NewDataOutput creates an unspendable output carrying data
+func NewDataOutput(data []byte) (*TXOutput, error) {
+	if len(data) > maxDataCarrierSize {
+		return nil, fmt.Errorf("Data is longer than %d bytes", maxDataCarrierSize)
+	}
+
+	return &TXOutput{0, NewDataScript(data)}, nil
+}
+
+// HasValidDataOutputs checks that every data carrier output of the
+// transaction is well formed and within the size limit
+func (tx Transaction) HasValidDataOutputs() bool {
+	for _, out := range tx.Vout {
+		if !out.IsUnspendable() {
+			continue
+		}
+
+		data := out.ScriptPubKey.CarriedData()
+		if data == nil || len(data) > maxDataCarrierSize {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewDataTransaction creates a Transaction embedding data in the chain,
+// paid for with the smallest output of the from address worth spending.
+// The rest goes back to from
+func NewDataTransaction(wallets *Wallets, from string, data []byte, feePerInput int, UTXOSet *UTXOSet) (*Transaction, error) {
+	wallet, err := wallets.SpendingWallet(from)
+	if err != nil {
+		return nil, err
+	}
+
+	dataOutput, err := NewDataOutput(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// At least one input is needed, leaving at least 1 as change
+	UTXOs := UTXOSet.FindSpendableUTXOs(HashPubKey(wallet.PublicKey))
+	selected, err := SmallestFirstSelector{}.Select(UTXOs, 1, feePerInput)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("No output to pay for the data")
+	}
+
+	utxo := selected[0]
+	change := NewTXOutput(utxo.Output.Value-feePerInput, from)
+	tx := Transaction{nil, []TXInput{{utxo.Txid, utxo.Vout, nil, 0}}, []TXOutput{*dataOutput, *change}, 0, nil}
+
+	UTXOSet.Blockchain.SignTransactionWithWallets(&tx, wallets)
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
+
+// FindDataOutputs returns the data outputs of the blockchain whose data
+// starts with prefix, newest first
+func (bc *Blockchain) FindDataOutputs(prefix []byte) []DataOutput {
+	found := []DataOutput{}
+	bci := bc.Iterator()
+
+	for {
+		block := bci.Next()
+
+		for _, tx := range block.Transactions {
+			for index, out := range tx.Vout {
+				if !out.IsUnspendable() {
+					continue
+				}
+
+				data := out.ScriptPubKey.CarriedData()
+				if data != nil && bytes.HasPrefix(data, prefix) {
+					found = append(found, DataOutput{tx.ID, index, block.Height, block.Timestamp, data})
+				}
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return found
+}
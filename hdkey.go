@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Child numbers from HardenedKeyStart on derive hardened keys, which
+// can't be derived from the extended public key of their parent
+const HardenedKeyStart = uint32(0x80000000)
+
+const extendedKeyLen = 78
+
+var errInvalidChild = errors.New("Child key is invalid, use the next index")
+var errInvalidExtendedKey = errors.New("Extended key is not valid")
+
+// Key of the HMAC making a master key from a seed, and version bytes of
+// serialized private and public extended keys. secp256k1 ones are BIP32's
+var extendedKeyParams = map[KeyType]struct {
+	seedKey         string
+	private, public uint32
+}{
+	KeyTypeP256:      {"Nist256p1 seed", 0x0420e6a3, 0x0420eb1b},
+	KeyTypeSecp256k1: {"Bitcoin seed", 0x0488ade4, 0x0488b21e},
+}
+
+// ExtendedKey is a BIP32 key with the chain code to derive its children
+type ExtendedKey struct {
+	KeyType     KeyType
+	Depth       byte
+	ParentFP    []byte
+	ChildNumber uint32
+	ChainCode   []byte
+	// 32 byte private key, or 33 byte compressed SEC1 public key
+	Key     []byte
+	Private bool
+}
+
+// NewMasterKey derives the master key of a seed for keys of keyType
+func NewMasterKey(seed []byte, keyType KeyType) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("Seed must be 16 to 64 bytes long")
+	}
+
+	mac := hmac.New(sha512.New, []byte(extendedKeyParams[keyType].seedKey))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(keyType.Curve().Params().N) >= 0 {
+		return nil, errors.New("Seed gives an invalid master key")
+	}
+
+	return &ExtendedKey{keyType, 0, make([]byte, 4), 0, sum[32:], sum[:32], true}, nil
+}
+
+// Returns the compressed SEC1 public key, without the key type
+func (k *ExtendedKey) sec1PubKey() []byte {
+	if !k.Private {
+		return k.Key
+	}
+
+	curve := k.KeyType.Curve()
+	x, y := curve.ScalarBaseMult(k.Key)
+
+	return elliptic.MarshalCompressed(curve, x, y)
+}
+
+// Child derives the child key with the given number, hardened from
+// HardenedKeyStart on. Extended public keys only have non-hardened children
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	if !k.Private && i >= HardenedKeyStart {
+		return nil, errors.New("Can't derive a hardened key from a public key")
+	}
+
+	var data []byte
+	if i >= HardenedKeyStart {
+		data = append([]byte{0x00}, k.Key...)
+	} else {
+		data = k.sec1PubKey()
+	}
+	data = binary.BigEndian.AppendUint32(data, i)
+
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	curve := k.KeyType.Curve()
+	n := curve.Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, errInvalidChild
+	}
+
+	child := ExtendedKey{k.KeyType, k.Depth + 1, HashPubKey(k.sec1PubKey())[:4], i, sum[32:], nil, k.Private}
+
+	if k.Private {
+		key := il.Add(il, new(big.Int).SetBytes(k.Key))
+		key.Mod(key, n)
+		if key.Sign() == 0 {
+			return nil, errInvalidChild
+		}
+		child.Key = bytes32(key)
+
+		return &child, nil
+	}
+
+	parentX, parentY := elliptic.UnmarshalCompressed(curve, k.Key)
+	x, y := curve.ScalarBaseMult(bytes32(il))
+	x, y = curve.Add(x, y, parentX, parentY)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errInvalidChild
+	}
+	child.Key = elliptic.MarshalCompressed(curve, x, y)
+
+	return &child, nil
+}
+
+// Derive derives the key at a path like "m/0'/1" from a master key. An
+// apostrophe or h marks hardened child numbers
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("Invalid key path '%s'", path)
+	}
+
+	key := k
+	for _, part := range parts[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			offset = HardenedKeyStart
+			part = part[:len(part)-1]
+		}
+
+		i, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid key path '%s'", path)
+		}
+
+		key, err = key.Child(uint32(i) + offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// Neuter returns the extended public key of an extended key
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	return &ExtendedKey{k.KeyType, k.Depth, k.ParentFP, k.ChildNumber, k.ChainCode, k.sec1PubKey(), false}
+}
+
+// PubKey returns the encoded public key, with its key type
+func (k *ExtendedKey) PubKey() []byte {
+	return withKeyType(k.KeyType.Curve(), k.sec1PubKey())
+}
+
+// PrivateKey returns the private key of an extended private key
+func (k *ExtendedKey) PrivateKey() ecdsa.PrivateKey {
+	return newPrivateKey(k.KeyType.Curve(), k.Key)
+}
+
+// String serializes the key in the Base58Check BIP32 format
+func (k *ExtendedKey) String() string {
+	version := extendedKeyParams[k.KeyType].public
+	key := k.Key
+	if k.Private {
+		version = extendedKeyParams[k.KeyType].private
+		key = append([]byte{0x00}, k.Key...)
+	}
+
+	payload := binary.BigEndian.AppendUint32(nil, version)
+	payload = append(payload, k.Depth)
+	payload = append(payload, k.ParentFP...)
+	payload = binary.BigEndian.AppendUint32(payload, k.ChildNumber)
+	payload = append(payload, k.ChainCode...)
+	payload = append(payload, key...)
+
+	return string(Base58Encode(append(payload, checksum(payload)...)))
+}
+
+// ParseExtendedKey parses a key serialized by ExtendedKey.String
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	data := Base58Decode([]byte(s))
+	if len(data) != extendedKeyLen+addressChecksumLen {
+		return nil, errInvalidExtendedKey
+	}
+
+	payload := data[:extendedKeyLen]
+	if !bytes.Equal(checksum(payload), data[extendedKeyLen:]) {
+		return nil, errInvalidExtendedKey
+	}
+
+	key := ExtendedKey{
+		Depth:       payload[4],
+		ParentFP:    payload[5:9],
+		ChildNumber: binary.BigEndian.Uint32(payload[9:13]),
+		ChainCode:   payload[13:45],
+		Key:         payload[45:],
+	}
+
+	version := binary.BigEndian.Uint32(payload[:4])
+	found := false
+	for keyType, params := range extendedKeyParams {
+		if version == params.private || version == params.public {
+			key.KeyType = keyType
+			key.Private = version == params.private
+			found = true
+		}
+	}
+	if !found {
+		return nil, errInvalidExtendedKey
+	}
+
+	curve := key.KeyType.Curve()
+	if key.Private {
+		d := new(big.Int).SetBytes(key.Key[1:])
+		if key.Key[0] != 0x00 || d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+			return nil, errInvalidExtendedKey
+		}
+		key.Key = key.Key[1:]
+	} else if _, err := parseSEC1PubKey(curve, key.Key); err != nil {
+		return nil, errInvalidExtendedKey
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vector 1 from BIP32
+func TestExtendedKeyVectors(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed, KeyTypeSecp256k1)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		path, xpub, xprv string
+	}{
+		{
+			"m",
+			"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+			"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+		},
+		{
+			"m/0'",
+			"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+			"xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+		},
+		{
+			"m/0'/1",
+			"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+			"xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+		},
+	}
+
+	for _, test := range tests {
+		key, err := master.Derive(test.path)
+		assert.Nil(t, err)
+		assert.Equal(t, test.xprv, key.String(), "xprv of %s", test.path)
+		assert.Equal(t, test.xpub, key.Neuter().String(), "xpub of %s", test.path)
+
+		parsed, err := ParseExtendedKey(test.xprv)
+		assert.Nil(t, err)
+		assert.Equal(t, key, parsed)
+	}
+
+	// Public derivation gives the public key of private derivation
+	parent, _ := ParseExtendedKey(tests[1].xpub)
+	child, err := parent.Child(1)
+	assert.Nil(t, err)
+	assert.Equal(t, tests[2].xpub, child.String())
+
+	_, err = parent.Child(HardenedKeyStart)
+	assert.NotNil(t, err, "Hardened keys need the private key")
+
+	_, err = ParseExtendedKey(tests[0].xpub[:len(tests[0].xpub)-1] + "9")
+	assert.Equal(t, errInvalidExtendedKey, err)
+}
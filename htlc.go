@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Length of HTLC secrets. The script checks it, so a secret that can be
+// revealed on another chain can always be revealed here too
+const htlcPreimageLen = 32
+
+// HTLC is a hash time-locked contract. Its outputs can be redeemed by the
+// recipient revealing the secret hashing to Hash, or refunded to the
+// sender once Timeout has been reached
+type HTLC struct {
+	Hash      []byte
+	Recipient []byte // Public key hash of the recipient
+	Sender    []byte // Public key hash of the sender
+	Timeout   int    // Block height or unix time, like a lock time
+}
+
+// Script returns the redeem script of the contract
+func (h HTLC) Script() Script {
+	return NewScriptBuilder().
+		AddOp(OP_IF).
+		AddOp(OP_SIZE).
+		AddNum(htlcPreimageLen).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_SHA256).
+		AddData(h.Hash).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(h.Recipient).
+		AddOp(OP_ELSE).
+		AddNum(h.Timeout).
+		AddOp(OP_CHECKLOCKTIMEVERIFY).
+		AddOp(OP_DROP).
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(h.Sender).
+		AddOp(OP_ENDIF).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// ParseHTLC returns the contract of an HTLC redeem script, or nil
+func ParseHTLC(script Script) *HTLC {
+	ops, err := script.parse()
+	if err != nil || len(ops) != 20 {
+		return nil
+	}
+
+	timeout, ok := decodeScriptNum(ops[11].pushedValue())
+	if !ok {
+		return nil
+	}
+
+	// Rebuilding the script checks all the opcodes at once
+	h := HTLC{ops[5].data, ops[9].data, ops[16].data, timeout}
+	if len(h.Hash) != sha256.Size || len(h.Recipient) != 20 || len(h.Sender) != 20 || !bytes.Equal(h.Script(), script) {
+		return nil
+	}
+
+	return &h
+}
+
+// NewHTLCTransaction creates a Transaction sending all outputs of the
+// contract to the to address. With a preimage it redeems them with the key
+// of the recipient, without one it refunds them with the key of the sender
+func NewHTLCTransaction(wallets *Wallets, h HTLC, preimage []byte, to string, feePerInput int, UTXOSet *UTXOSet) (*Transaction, error) {
+	if preimage != nil && len(preimage) != htlcPreimageLen {
+		return nil, fmt.Errorf("Preimage must be %d bytes long", htlcPreimageLen)
+	}
+
+	redeemScript := h.Script()
+	UTXOs := UTXOSet.FindSpendableUTXOs(HashPubKey(redeemScript))
+	if len(UTXOs) == 0 {
+		return nil, errors.New("Contract has no spendable outputs")
+	}
+
+	pubKeyHash := h.Sender
+	if preimage != nil {
+		hash := sha256.Sum256(preimage)
+		if !bytes.Equal(hash[:], h.Hash) {
+			return nil, errors.New("Preimage doesn't match the contract hash")
+		}
+		pubKeyHash = h.Recipient
+	}
+
+	wallet := wallets.GetWalletByPubKeyHash(pubKeyHash)
+	if wallet == nil {
+		return nil, errors.New("Key of the contract party is not in the wallet")
+	}
+	signer, err := wallet.Signer()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := Transaction{nil, []TXInput{}, []TXOutput{}, 0, nil}
+	amount := 0
+	for _, utxo := range UTXOs {
+		tx.Vin = append(tx.Vin, TXInput{utxo.Txid, utxo.Vout, nil, 0})
+		amount += utxo.Output.Value - feePerInput
+	}
+	if amount <= 0 {
+		return nil, errNotEnoughFunds
+	}
+	tx.Vout = append(tx.Vout, *NewTXOutput(amount, to))
+
+	// A refund can only be mined once the timeout is reached
+	if preimage == nil {
+		tx.LockTime = h.Timeout
+	}
+
+	hashes := NewSigHashes(&tx)
+	for index, utxo := range UTXOs {
+		signature := tx.InputSignature(hashes, index, signer, utxo.Output, SigHashAll)
+
+		builder := NewScriptBuilder().AddData(signature).AddData(wallet.PublicKey)
+		if preimage != nil {
+			builder.AddData(preimage).AddInt(1)
+		} else {
+			builder.AddInt(0)
+		}
+		tx.SetWitness(index, builder.AddData(redeemScript).Script())
+	}
+
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
+
+// ExtractPreimage returns the secret revealed by the first input of tx
+// redeeming an HTLC
+func ExtractPreimage(tx *Transaction) ([]byte, error) {
+	for index := range tx.Vin {
+		pushes := tx.InputWitness(index).PushedData()
+		if len(pushes) != 5 || !castToBool(pushes[3]) {
+			continue
+		}
+
+		h := ParseHTLC(pushes[4])
+		if h == nil {
+			continue
+		}
+
+		hash := sha256.Sum256(pushes[2])
+		if bytes.Equal(hash[:], h.Hash) {
+			return pushes[2], nil
+		}
+	}
+
+	return nil, fmt.Errorf("Transaction %x doesn't redeem an HTLC", tx.ID)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTLCScript(t *testing.T) {
+	secret := bytes.Repeat([]byte("s"), htlcPreimageLen)
+	hash := sha256.Sum256(secret)
+	recipient := []byte("recipient key")
+	sender := []byte("sender key")
+
+	h := HTLC{hash[:], HashPubKey(recipient), HashPubKey(sender), 100}
+	redeemScript := h.Script()
+	scriptPubKey := NewP2SHScript(HashPubKey(redeemScript))
+
+	assert.Equal(t, &h, ParseHTLC(redeemScript))
+	assert.Nil(t, ParseHTLC(NewP2PKHScript(HashPubKey(recipient))))
+
+	redeem := func(pubKey, preimage []byte) Script {
+		return NewScriptBuilder().AddData(pubKey).AddData(pubKey).AddData(preimage).AddInt(1).AddData(redeemScript).Script()
+	}
+	refund := func(pubKey []byte) Script {
+		return NewScriptBuilder().AddData(pubKey).AddData(pubKey).AddInt(0).AddData(redeemScript).Script()
+	}
+
+	err := VerifyScript(redeem(recipient, secret), scriptPubKey, testChecker{})
+	assert.Nil(t, err, "Recipient redeems with the secret")
+
+	err = VerifyScript(redeem(recipient, []byte("guess")), scriptPubKey, testChecker{})
+	assert.Equal(t, errScriptVerify, err, "Wrong secret is rejected")
+
+	// A secret of another length is rejected even when it hashes right, so
+	// one too long to push here can't be used to claim the other leg
+	for _, length := range []int{htlcPreimageLen - 1, htlcPreimageLen + 1, maxScriptElement} {
+		longSecret := bytes.Repeat([]byte("s"), length)
+		longHash := sha256.Sum256(longSecret)
+		long := HTLC{longHash[:], h.Recipient, h.Sender, h.Timeout}
+		longScript := long.Script()
+		witness := NewScriptBuilder().AddData(recipient).AddData(recipient).AddData(longSecret).AddInt(1).AddData(longScript).Script()
+
+		err = VerifyScript(witness, NewP2SHScript(HashPubKey(longScript)), testChecker{})
+		assert.Equal(t, errScriptVerify, err, "Secret of %d bytes", length)
+		_, err = NewHTLCTransaction(newWallets(), long, longSecret, "", 0, nil)
+		assert.NotNil(t, err, "Secret of %d bytes", length)
+	}
+
+	err = VerifyScript(redeem(sender, secret), scriptPubKey, testChecker{})
+	assert.Equal(t, errScriptVerify, err, "Only the recipient can redeem")
+
+	err = VerifyScript(refund(sender), scriptPubKey, testChecker{lockTime: 99})
+	assert.Equal(t, errScriptLocked, err, "Refund waits for the timeout")
+
+	err = VerifyScript(refund(sender), scriptPubKey, testChecker{lockTime: 100})
+	assert.Nil(t, err, "Sender refunds after the timeout")
+
+	tx := Transaction{Vin: []TXInput{{[]byte{1}, 0, nil, 0}}, Witness: []Script{redeem(recipient, secret)}}
+	preimage, err := ExtractPreimage(&tx)
+	assert.Nil(t, err)
+	assert.Equal(t, secret, preimage)
+
+	tx.Witness[0] = refund(sender)
+	_, err = ExtractPreimage(&tx)
+	assert.NotNil(t, err, "Refunds reveal no secret")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// KeyType is the curve of a key pair
+type KeyType byte
+
+const (
+	KeyTypeP256 KeyType = iota
+	KeyTypeSecp256k1
+)
+
+var keyTypeNames = map[KeyType]string{
+	KeyTypeP256:      "p256",
+	KeyTypeSecp256k1: "secp256k1",
+}
+
+// ParseKeyType parses a key type name like "secp256k1"
+func ParseKeyType(name string) (KeyType, error) {
+	for keyType, keyTypeName := range keyTypeNames {
+		if strings.EqualFold(name, keyTypeName) {
+			return keyType, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown key type '%s'", name)
+}
+
+func (t KeyType) String() string {
+	if name, ok := keyTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("KeyType(%d)", byte(t))
+}
+
+// IsValid checks if t is a known key type
+func (t KeyType) IsValid() bool {
+	_, ok := keyTypeNames[t]
+
+	return ok
+}
+
+// Curve returns the curve keys of the type are on
+func (t KeyType) Curve() elliptic.Curve {
+	switch t {
+	case KeyTypeP256:
+		return elliptic.P256()
+	case KeyTypeSecp256k1:
+		return Secp256k1()
+	}
+
+	return nil
+}
+
+// Returns the key type of keys on curve. Keys are only ever made on the
+// curves of key types, any other one is a bug
+func curveKeyType(curve elliptic.Curve) KeyType {
+	for keyType := range keyTypeNames {
+		if keyType.Curve().Params().Name == curve.Params().Name {
+			return keyType
+		}
+	}
+
+	log.Panicf("ERROR: Unsupported curve %s", curve.Params().Name)
+	return 0
+}
+
+// Signer signs hashes with a private key
+type Signer interface {
+	// PubKey returns the encoded public key checking the signatures
+	PubKey() []byte
+	Sign(hash []byte) []byte
+}
+
+// Verifier checks signatures of hashes against encoded public keys
+type Verifier interface {
+	VerifyHash(pubKey, hash, signature []byte) bool
+}
+
+// ecdsaSigner is a Signer making ECDSA signatures
+type ecdsaSigner struct {
+	privKey ecdsa.PrivateKey
+	pubKey  []byte
+}
+
+// NewSigner returns the Signer of an ECDSA private key
+func NewSigner(privKey ecdsa.PrivateKey) Signer {
+	return ecdsaSigner{privKey, encodePubKey(privKey.PublicKey)}
+}
+
+func (s ecdsaSigner) PubKey() []byte {
+	return s.pubKey
+}
+
+func (s ecdsaSigner) Sign(hash []byte) []byte {
+	return SignHash(s.privKey, hash)
+}
+
+// signatureVerifier is a Verifier of the signatures of keys of some types.
+// With a batch, Schnorr signatures are queued into it instead of checked
+type signatureVerifier struct {
+	keyTypes []KeyType
+	batch    *SchnorrBatch
+}
+
+// NewVerifier returns a Verifier accepting keys of the given types only
+func NewVerifier(keyTypes ...KeyType) Verifier {
+	return signatureVerifier{keyTypes, nil}
+}
+
+// Returns a Verifier accepting keys of every type
+func anyKeyVerifier() Verifier {
+	keyTypes := []KeyType{}
+	for keyType := range keyTypeNames {
+		keyTypes = append(keyTypes, keyType)
+	}
+
+	return NewVerifier(keyTypes...)
+}
+
+func (v signatureVerifier) VerifyHash(pubKey, hash, signature []byte) bool {
+	rawPubKey, err := ParsePubKey(pubKey)
+	if err != nil || !v.allows(curveKeyType(rawPubKey.Curve)) {
+		return false
+	}
+
+	signature, sigType := splitSigType(signature)
+	if sigType == SigTypeECDSA {
+		return verifyECDSA(rawPubKey, hash, signature)
+	}
+
+	if v.batch != nil && curveKeyType(rawPubKey.Curve) == KeyTypeSecp256k1 {
+		v.batch.Add(rawPubKey, hash, signature)
+		return true
+	}
+
+	return verifySchnorr(rawPubKey, hash, signature)
+}
+
+func (v signatureVerifier) allows(keyType KeyType) bool {
+	for _, allowed := range v.keyTypes {
+		if keyType == allowed {
+			return true
+		}
+	}
+
+	return false
+}
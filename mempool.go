@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/boltdb/bolt"
+)
+
+const mempoolBucket = "mempool"
+
+// Mempool keeps valid transactions waiting to be mined
+type Mempool struct {
+	Blockchain *Blockchain
+}
+
+var errUnconfirmedInput = errors.New("Transaction spends an output of a transaction in the pool, add it once that one is mined")
+
+// Add verifies a transaction and puts it into the pool. Its inputs must
+// spend confirmed outputs, the pool doesn't hold chains of unconfirmed
+// transactions
+func (m Mempool) Add(transaction *Transaction) error {
+	if transaction.IsCoinbase() {
+		return errors.New("Coinbase transactions can't be added to the pool")
+	}
+	if !bytes.Equal(transaction.ID, transaction.Hash()) {
+		return errors.New("Transaction ID doesn't match its hash")
+	}
+
+	pooled := make(map[string]bool)
+	for _, tx := range m.Transactions() {
+		pooled[string(tx.ID)] = true
+	}
+	for _, vin := range transaction.Vin {
+		if pooled[string(vin.Txid)] {
+			return errUnconfirmedInput
+		}
+	}
+
+	err := m.Blockchain.VerifyTransactions([]*Transaction{transaction})
+	if err != nil {
+		return err
+	}
+
+	spent := m.spentOutputs()
+	for _, vin := range transaction.Vin {
+		if spent[outpointKey(vin.Txid, vin.Vout)] {
+			return errors.New("Transaction conflicts with a transaction in the pool")
+		}
+	}
+
+	err = m.Blockchain.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(mempoolBucket))
+		if err != nil {
+			return err
+		}
+
+		return b.Put(transaction.ID, transaction.Serialize())
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return nil
+}
+
+// Transactions returns all transactions in the pool
+func (m Mempool) Transactions() []*Transaction {
+	transactions := []*Transaction{}
+
+	err := m.Blockchain.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(mempoolBucket))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(key, value []byte) error {
+			transaction := DeserializeTransaction(value)
+			transactions = append(transactions, &transaction)
+			return nil
+		})
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return transactions
+}
+
+// Update removes the transactions included in the Block from the pool
+// together with the ones spending the same outputs
+func (m Mempool) Update(block *Block) {
+	spent := make(map[string]bool)
+	for _, tx := range block.Transactions {
+		for _, vin := range tx.Vin {
+			spent[outpointKey(vin.Txid, vin.Vout)] = true
+		}
+	}
+
+	transactions := m.Transactions()
+
+	err := m.Blockchain.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(mempoolBucket))
+		if b == nil {
+			return nil
+		}
+
+		for _, transaction := range transactions {
+			for _, vin := range transaction.Vin {
+				if spent[outpointKey(vin.Txid, vin.Vout)] {
+					err := b.Delete(transaction.ID)
+					if err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// Returns the outputs spent by the transactions in the pool
+func (m Mempool) spentOutputs() map[string]bool {
+	spent := make(map[string]bool)
+
+	for _, transaction := range m.Transactions() {
+		for _, vin := range transaction.Vin {
+			spent[outpointKey(vin.Txid, vin.Vout)] = true
+		}
+	}
+
+	return spent
+}
+
+// Returns a string identifying an output of a transaction
+func outpointKey(txid []byte, vout int) string {
+	return fmt.Sprintf("%x:%d", txid, vout)
+}
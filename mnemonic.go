@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// A mnemonic encodes 128 to 256 bits of entropy as 12 to 24 words of 11
+// bits each, the last word ending with a checksum of the entropy
+
+var errInvalidMnemonic = errors.New("Mnemonic is not valid")
+
+// NewMnemonic generates a random mnemonic of 12, 15, 18, 21 or 24 words
+func NewMnemonic(words int) (string, error) {
+	if words < 12 || words > 24 || words%3 != 0 {
+		return "", fmt.Errorf("A mnemonic has 12, 15, 18, 21 or 24 words, not %d", words)
+	}
+
+	entropy := make([]byte, words*4/3)
+	_, err := rand.Read(entropy)
+	if err != nil {
+		return "", err
+	}
+
+	return entropyToMnemonic(entropy), nil
+}
+
+// Returns the mnemonic encoding entropy followed by its checksum
+func entropyToMnemonic(entropy []byte) string {
+	checksumBits := len(entropy) / 4
+	hash := sha256.Sum256(entropy)
+
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, uint(checksumBits))
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	words := make([]string, (len(entropy)*8+checksumBits)/11)
+	index := new(big.Int)
+	for i := len(words) - 1; i >= 0; i-- {
+		index.And(data, big.NewInt(2047))
+		words[i] = englishWords[index.Int64()]
+		data.Rsh(data, 11)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Returns the entropy encoded by a mnemonic, checking its words and
+// checksum
+func mnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, errInvalidMnemonic
+	}
+
+	data := new(big.Int)
+	for _, word := range words {
+		index := wordIndex(word)
+		if index < 0 {
+			return nil, fmt.Errorf("Unknown mnemonic word '%s'", word)
+		}
+
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+
+	checksumBits := len(words) / 3
+	checksum := new(big.Int).And(data, big.NewInt(1<<checksumBits-1))
+	entropy := make([]byte, len(words)*4/3)
+	data.Rsh(data, uint(checksumBits)).FillBytes(entropy)
+
+	hash := sha256.Sum256(entropy)
+	if int64(hash[0]>>(8-checksumBits)) != checksum.Int64() {
+		return nil, errInvalidMnemonic
+	}
+
+	return entropy, nil
+}
+
+// Returns the index of a word in the wordlist, or -1
+func wordIndex(word string) int {
+	word = strings.ToLower(word)
+	for i, w := range englishWords {
+		if w == word {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ValidateMnemonic checks the words and the checksum of a mnemonic
+func ValidateMnemonic(mnemonic string) error {
+	_, err := mnemonicToEntropy(mnemonic)
+
+	return err
+}
+
+// MnemonicSeed returns the 64 byte seed of a mnemonic. A different
+// passphrase gives a different seed, and so different keys. The passphrase
+// isn't Unicode normalized, other wallets only agree on ASCII ones
+func MnemonicSeed(mnemonic, passphrase string) []byte {
+	mnemonic = strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+
+	return pbkdf2.Key([]byte(mnemonic), []byte("mnemonic"+passphrase), 2048, 64, sha512.New)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+)
+
+// MuSig lets several secp256k1 keys sign together for a single aggregate
+// key, so a multi-party output looks like any other key on chain. Signing
+// takes two rounds: every signer shares a public nonce, then a partial
+// signature. The partial signatures add up to a Schnorr signature
+//
+// MuSig is a library API only, the CLI has no commands for it: each CLI
+// command is a separate process, so the secret nonce would have to be
+// saved on disk between the two rounds, and a nonce used twice reveals
+// the private key
+
+var errMuSigKeyNotFound = errors.New("Key is not part of the MuSig session")
+
+// AggregatePubKeys returns the MuSig aggregate of encoded secp256k1
+// public keys. Each key is weighted by a coefficient committing to all
+// keys, so no signer can choose a key cancelling the others out
+func AggregatePubKeys(pubKeys [][]byte) ([]byte, error) {
+	aggregate, _, err := aggregateKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodePubKey(*aggregate), nil
+}
+
+// Returns the aggregate key and the coefficient of each key
+func aggregateKeys(pubKeys [][]byte) (*ecdsa.PublicKey, []*big.Int, error) {
+	if len(pubKeys) == 0 {
+		return nil, nil, errors.New("No keys to aggregate")
+	}
+
+	points := [][2]*big.Int{}
+	keyList := []byte{}
+	for _, pubKey := range pubKeys {
+		rawPubKey, err := ParsePubKey(pubKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		if curveKeyType(rawPubKey.Curve) != KeyTypeSecp256k1 {
+			return nil, nil, errors.New("MuSig needs secp256k1 keys")
+		}
+
+		points = append(points, [2]*big.Int{rawPubKey.X, rawPubKey.Y})
+		keyList = append(keyList, elliptic.MarshalCompressed(secp256k1, rawPubKey.X, rawPubKey.Y)...)
+	}
+
+	listHash := taggedHash("KeyAgg list", keyList)
+	coefficients := []*big.Int{}
+	for i := range points {
+		coefficient := new(big.Int).SetBytes(taggedHash("KeyAgg coefficient", listHash, keyList[i*33:(i+1)*33]))
+		coefficients = append(coefficients, coefficient.Mod(coefficient, secp256k1.params.N))
+	}
+
+	x, y := secp256k1.multiScalarMult(points, coefficients)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, nil, errors.New("Aggregate key is the point at infinity")
+	}
+
+	return &ecdsa.PublicKey{Curve: secp256k1, X: x, Y: y}, coefficients, nil
+}
+
+// MuSigNonce is the secret nonce of a signer for one signing session.
+// Using it for two sessions reveals the private key
+type MuSigNonce struct {
+	k1, k2 *big.Int
+}
+
+// NewMuSigNonce generates a fresh secret nonce
+func NewMuSigNonce() *MuSigNonce {
+	nonce := MuSigNonce{}
+	for _, k := range []**big.Int{&nonce.k1, &nonce.k2} {
+		var err error
+		*k, err = rand.Int(rand.Reader, new(big.Int).Sub(secp256k1.params.N, big.NewInt(1)))
+		if err != nil {
+			log.Panic(err)
+		}
+		(*k).Add(*k, big.NewInt(1))
+	}
+
+	return &nonce
+}
+
+// Public returns the public nonce to share with the other signers
+func (n MuSigNonce) Public() []byte {
+	x1, y1 := secp256k1.ScalarBaseMult(bytes32(n.k1))
+	x2, y2 := secp256k1.ScalarBaseMult(bytes32(n.k2))
+
+	return append(elliptic.MarshalCompressed(secp256k1, x1, y1), elliptic.MarshalCompressed(secp256k1, x2, y2)...)
+}
+
+// MuSigSession holds what every signer needs to sign a hash with an
+// aggregate key, once all public nonces are known
+type MuSigSession struct {
+	pubKeys      [][]byte
+	coefficients []*big.Int
+	aggregate    *ecdsa.PublicKey
+	hash         []byte
+	nonceCoef    *big.Int
+	rx           *big.Int
+	ry           *big.Int
+	challenge    *big.Int
+}
+
+// NewMuSigSession starts signing hash by the aggregate of pubKeys, with
+// the public nonces of all the signers
+func NewMuSigSession(pubKeys [][]byte, publicNonces [][]byte, hash []byte) (*MuSigSession, error) {
+	aggregate, coefficients, err := aggregateKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sum the first and the second nonce points of all the signers
+	r1x, r1y := new(big.Int), new(big.Int)
+	r2x, r2y := new(big.Int), new(big.Int)
+	for _, publicNonce := range publicNonces {
+		if len(publicNonce) != 66 {
+			return nil, errors.New("Invalid MuSig public nonce")
+		}
+
+		x1, y1 := elliptic.UnmarshalCompressed(secp256k1, publicNonce[:33])
+		x2, y2 := elliptic.UnmarshalCompressed(secp256k1, publicNonce[33:])
+		if x1 == nil || x2 == nil {
+			return nil, errors.New("Invalid MuSig public nonce")
+		}
+
+		r1x, r1y = secp256k1.Add(r1x, r1y, x1, y1)
+		r2x, r2y = secp256k1.Add(r2x, r2y, x2, y2)
+	}
+
+	nonceCoef := new(big.Int).SetBytes(taggedHash("MuSig/noncecoef",
+		elliptic.MarshalCompressed(secp256k1, r1x, r1y),
+		elliptic.MarshalCompressed(secp256k1, r2x, r2y),
+		bytes32(aggregate.X), hash))
+	nonceCoef.Mod(nonceCoef, secp256k1.params.N)
+
+	// R = R1 + b·R2, which is G in the unlikely case it's at infinity
+	x, y := secp256k1.ScalarMult(r2x, r2y, bytes32(nonceCoef))
+	rx, ry := secp256k1.Add(r1x, r1y, x, y)
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		rx, ry = secp256k1.params.Gx, secp256k1.params.Gy
+	}
+
+	session := MuSigSession{pubKeys, coefficients, aggregate, hash, nonceCoef, rx, ry, schnorrChallenge(rx, aggregate.X, hash)}
+
+	return &session, nil
+}
+
+// AggregatePubKey returns the encoded key the session signs for
+func (s *MuSigSession) AggregatePubKey() []byte {
+	return encodePubKey(*s.aggregate)
+}
+
+// PartialSign returns the partial signature of a signer. The nonce must
+// be the one whose public nonce started the session
+func (s *MuSigSession) PartialSign(privKey ecdsa.PrivateKey, nonce *MuSigNonce) ([]byte, error) {
+	n := secp256k1.params.N
+	pubKey := encodePubKey(privKey.PublicKey)
+
+	index := -1
+	for i, key := range s.pubKeys {
+		if string(key) == string(pubKey) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, errMuSigKeyNotFound
+	}
+
+	// The signature is made for the x-only R and aggregate key, both with
+	// an even y, so the secrets of odd points are negated
+	k := new(big.Int).Mul(nonce.k2, s.nonceCoef)
+	k.Add(k, nonce.k1)
+	if s.ry.Bit(0) == 1 {
+		k.Sub(n, k.Mod(k, n))
+	}
+
+	d := new(big.Int).Mul(s.coefficients[index], privKey.D)
+	if s.aggregate.Y.Bit(0) == 1 {
+		d.Neg(d)
+	}
+
+	partial := d.Mul(d, s.challenge)
+	partial.Add(partial, k)
+
+	return bytes32(partial.Mod(partial, n)), nil
+}
+
+// Aggregate adds up the partial signatures of all the signers into a
+// Schnorr signature valid for the aggregate key
+func (s *MuSigSession) Aggregate(partialSigs [][]byte) []byte {
+	sum := new(big.Int)
+	for _, partial := range partialSigs {
+		sum.Add(sum, new(big.Int).SetBytes(partial))
+	}
+	sum.Mod(sum, secp256k1.params.N)
+
+	return append(bytes32(s.rx), bytes32(sum)...)
+}
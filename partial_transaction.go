@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+// PartialInput holds what is needed to sign an input without the chain
+type PartialInput struct {
+	PrevOutput TXOutput
+	// Redeem script of a P2SH output, once known
+	RedeemScript Script
+	// Signatures collected so far, keyed by hex encoded public key
+	Signatures map[string][]byte
+}
+
+// PartialTransaction is an unsigned transaction together with the outputs it
+// spends and the signatures collected for it. It can be passed between
+// machines, so keys can sign offline
+type PartialTransaction struct {
+	Tx     Transaction
+	Inputs []PartialInput
+}
+
+// NewPartialTransaction creates a PartialTransaction for an unsigned
+// transaction, prevTXs holding the transactions referenced by its inputs
+func NewPartialTransaction(tx *Transaction, prevTXs map[string]Transaction) *PartialTransaction {
+	ptx := PartialTransaction{tx.TrimmedCopy(), []PartialInput{}}
+	ptx.Tx.ID = nil
+
+	for index, vin := range tx.Vin {
+		prevTx, ok := prevTXs[hex.EncodeToString(vin.Txid)]
+		if !ok || vin.Vout < 0 || vin.Vout >= len(prevTx.Vout) {
+			log.Panicf("ERROR: Input %d references a missing output", index)
+		}
+
+		input := PartialInput{prevTx.Vout[vin.Vout], nil, make(map[string][]byte)}
+		ptx.Inputs = append(ptx.Inputs, input)
+	}
+
+	return &ptx
+}
+
+// Sign adds signatures of sigType for every input locked with a key from
+// wallets, directly or through a multisig or timelock redeem script, and
+// returns the number of signatures added
+func (ptx *PartialTransaction) Sign(wallets *Wallets, hashType SigHashType, sigType SigType) (int, error) {
+	signed := 0
+	hashes := NewSigHashes(&ptx.Tx)
+
+	for index := range ptx.Inputs {
+		input := &ptx.Inputs[index]
+		scriptHash := input.PrevOutput.ScriptPubKey.ScriptHash()
+		if scriptHash != nil && input.RedeemScript == nil {
+			input.RedeemScript = wallets.GetScript(scriptHash)
+		}
+
+		for _, pubKeyHash := range input.signerHashes() {
+			wallet := wallets.GetWalletByPubKeyHash(pubKeyHash)
+			if wallet == nil {
+				continue
+			}
+
+			signer, err := wallet.SignerOfType(sigType)
+			if err != nil {
+				return signed, err
+			}
+
+			signature := ptx.Tx.InputSignature(hashes, index, signer, input.PrevOutput, hashType)
+			input.Signatures[hex.EncodeToString(wallet.PublicKey)] = signature
+			signed++
+		}
+	}
+
+	return signed, nil
+}
+
+// Combine merges the signatures collected in other for the same transaction
+func (ptx *PartialTransaction) Combine(other *PartialTransaction) error {
+	if !bytes.Equal(ptx.Tx.Hash(), other.Tx.Hash()) || len(ptx.Inputs) != len(other.Inputs) {
+		return errors.New("Partial transactions spend different transactions")
+	}
+
+	for index, input := range other.Inputs {
+		if ptx.Inputs[index].RedeemScript == nil {
+			ptx.Inputs[index].RedeemScript = input.RedeemScript
+		}
+
+		for pubKey, signature := range input.Signatures {
+			ptx.Inputs[index].Signatures[pubKey] = signature
+		}
+	}
+
+	return nil
+}
+
+// Finalize builds the unlocking script of every input from the collected
+// signatures and returns the transaction ready to be broadcast
+func (ptx *PartialTransaction) Finalize() (*Transaction, error) {
+	tx := ptx.Tx.TrimmedCopy()
+	hashes := NewSigHashes(&tx)
+
+	for index, input := range ptx.Inputs {
+		checker := txSignatureChecker{&tx, index, input.PrevOutput, hashes, anyKeyVerifier()}
+		scriptSig := input.unlockingScript(checker)
+		if scriptSig == nil {
+			return nil, fmt.Errorf("Input %d is missing valid signatures", index)
+		}
+
+		err := VerifyScript(scriptSig, input.PrevOutput.ScriptPubKey, checker)
+		if err == errScriptLocked {
+			return nil, fmt.Errorf("Input %d needs a higher lock time or sequence", index)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Input %d is missing valid signatures", index)
+		}
+
+		tx.SetWitness(index, scriptSig)
+	}
+
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
+
+// IsInputSigned checks if an input has all the signatures it needs
+func (ptx *PartialTransaction) IsInputSigned(index int) bool {
+	checker := txSignatureChecker{&ptx.Tx, index, ptx.Inputs[index].PrevOutput, NewSigHashes(&ptx.Tx), anyKeyVerifier()}
+
+	return ptx.Inputs[index].unlockingScript(checker) != nil
+}
+
+// Returns the public key hashes of the keys that can sign the input
+func (input PartialInput) signerHashes() [][]byte {
+	if pubKeyHash := input.PrevOutput.ScriptPubKey.PubKeyHash(); pubKeyHash != nil {
+		return [][]byte{pubKeyHash}
+	}
+
+	if _, _, pubKeyHash := input.RedeemScript.Timelock(); pubKeyHash != nil {
+		return [][]byte{pubKeyHash}
+	}
+
+	hashes := [][]byte{}
+	_, pubKeys := input.RedeemScript.MultisigKeys()
+	for _, pubKey := range pubKeys {
+		hashes = append(hashes, HashPubKey(pubKey))
+	}
+
+	return hashes
+}
+
+// Builds the unlocking script from valid collected signatures, or returns
+// nil when there aren't enough of them
+func (input PartialInput) unlockingScript(checker SignatureChecker) Script {
+	if pubKeyHash := input.PrevOutput.ScriptPubKey.PubKeyHash(); pubKeyHash != nil {
+		return input.keyUnlockingScript(pubKeyHash, checker)
+	}
+
+	// A timelock redeem script is unlocked like P2PKH, followed by the script
+	if _, _, pubKeyHash := input.RedeemScript.Timelock(); pubKeyHash != nil {
+		scriptSig := input.keyUnlockingScript(pubKeyHash, checker)
+		if scriptSig == nil {
+			return nil
+		}
+
+		return append(scriptSig, NewScriptBuilder().AddData(input.RedeemScript).Script()...)
+	}
+
+	m, pubKeys := input.RedeemScript.MultisigKeys()
+	if m == 0 {
+		return nil
+	}
+
+	// Signatures have to follow the order of the keys in the redeem script
+	builder := NewScriptBuilder()
+	found := 0
+	for _, pubKey := range pubKeys {
+		signature, ok := input.Signatures[hex.EncodeToString(pubKey)]
+		if ok && found < m && checker.CheckSig(signature, pubKey) {
+			builder.AddData(signature)
+			found++
+		}
+	}
+
+	if found < m {
+		return nil
+	}
+
+	return builder.AddData(input.RedeemScript).Script()
+}
+
+// Builds a P2PKH unlocking script from a collected signature by the key
+// hashing to pubKeyHash, or returns nil
+func (input PartialInput) keyUnlockingScript(pubKeyHash []byte, checker SignatureChecker) Script {
+	for pubKeyHex, signature := range input.Signatures {
+		pubKey, err := hex.DecodeString(pubKeyHex)
+		if err == nil && bytes.Equal(HashPubKey(pubKey), pubKeyHash) && checker.CheckSig(signature, pubKey) {
+			return NewP2PKHScriptSig(signature, pubKey)
+		}
+	}
+
+	return nil
+}
+
+// Serialize serializes PartialTransaction
+func (ptx PartialTransaction) Serialize() []byte {
+	buff := bytes.Buffer{}
+
+	enc := gob.NewEncoder(&buff)
+	err := enc.Encode(ptx)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return buff.Bytes()
+}
+
+// DeserializePartialTransaction deserializes PartialTransaction
+func DeserializePartialTransaction(data []byte) *PartialTransaction {
+	ptx := PartialTransaction{}
+
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	err := dec.Decode(&ptx)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// gob leaves empty maps nil
+	for index := range ptx.Inputs {
+		if ptx.Inputs[index].Signatures == nil {
+			ptx.Inputs[index].Signatures = make(map[string][]byte)
+		}
+	}
+
+	return &ptx
+}
+
+// LoadPartialTransaction reads a PartialTransaction from a file
+func LoadPartialTransaction(file string) *PartialTransaction {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return DeserializePartialTransaction(content)
+}
+
+// SaveToFile writes the PartialTransaction to a file
+func (ptx PartialTransaction) SaveToFile(file string) {
+	err := ioutil.WriteFile(file, ptx.Serialize(), 0600)
+	if err != nil {
+		log.Panic(err)
+	}
+}
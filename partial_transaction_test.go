@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns Wallets holding only the key of wallet
+func walletsWithKey(t *testing.T, wallet *Wallet) *Wallets {
+	wallets := newWallets()
+	_, err := wallets.ImportKey(wallet.PrivateKey)
+	assert.Nil(t, err)
+
+	return wallets
+}
+
+// Mines a block with transactions and updates the UTXO set
+func mineTestBlock(bc *Blockchain, transactions ...*Transaction) {
+	block := bc.MineBlock(transactions)
+	UTXOSet{bc}.Update(block)
+}
+
+func TestPartialTransaction(t *testing.T) {
+	alice := NewWallet(KeyTypeP256)
+	bob := NewWallet(KeyTypeP256)
+	bc := newTestBlockchain(t, alice)
+	coinbase := NewCoinbaseTX(string(bob.GetAddress()), "")
+	mineTestBlock(bc, NewCoinbaseTX(string(alice.GetAddress()), ""), coinbase)
+
+	// One input paying Alice and one paying Bob, each signed by its owner
+	tx := &Transaction{nil, []TXInput{{genesisCoinbase(bc).ID, 0, nil, 0}, {coinbase.ID, 0, nil, 0}}, []TXOutput{*NewTXOutput(2*subsidy-1, string(alice.GetAddress()))}, 0, nil}
+	prevTXs := bc.findPrevTransactions(tx)
+	ptx := DeserializePartialTransaction(NewPartialTransaction(tx, prevTXs).Serialize())
+	bobPtx := DeserializePartialTransaction(ptx.Serialize())
+	tampered := DeserializePartialTransaction(ptx.Serialize())
+
+	signed, err := ptx.Sign(walletsWithKey(t, alice), SigHashAll, SigTypeECDSA)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, signed)
+	assert.True(t, ptx.IsInputSigned(0))
+	assert.False(t, ptx.IsInputSigned(1))
+	_, err = ptx.Finalize()
+	assert.NotNil(t, err, "Bob hasn't signed yet")
+
+	signed, err = bobPtx.Sign(walletsWithKey(t, bob), SigHashAll, SigTypeECDSA)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, signed)
+
+	assert.Nil(t, ptx.Combine(bobPtx))
+	final, err := ptx.Finalize()
+	assert.Nil(t, err)
+	assert.True(t, final.Verify(prevTXs))
+	assert.Nil(t, bc.VerifyTransactions([]*Transaction{final}))
+
+	// Signatures only hold for the spent outputs they were made for
+	changed := DeserializePartialTransaction(ptx.Serialize())
+	changed.Inputs[0].PrevOutput.Value++
+	_, err = changed.Finalize()
+	assert.NotNil(t, err, "Spent value changed after signing")
+
+	changed = DeserializePartialTransaction(ptx.Serialize())
+	changed.Inputs[1].PrevOutput.ScriptPubKey = NewP2PKHScript(HashPubKey(bob.PublicKey)[:19])
+	_, err = changed.Finalize()
+	assert.NotNil(t, err, "Spent script changed after signing")
+
+	// A value lied about before signing gives signatures the chain rejects
+	tampered.Inputs[0].PrevOutput.Value = subsidy * 2
+	tampered.Sign(walletsWithKey(t, alice), SigHashAll, SigTypeECDSA)
+	tampered.Sign(walletsWithKey(t, bob), SigHashAll, SigTypeECDSA)
+	lied, err := tampered.Finalize()
+	assert.Nil(t, err)
+	assert.False(t, lied.Verify(prevTXs))
+	assert.NotNil(t, bc.VerifyTransactions([]*Transaction{lied}))
+
+	other := &Transaction{nil, tx.Vin, []TXOutput{*NewTXOutput(subsidy, string(bob.GetAddress()))}, 0, nil}
+	assert.NotNil(t, ptx.Combine(NewPartialTransaction(other, prevTXs)), "Another transaction")
+}
+
+func TestMultisigAcrossWallets(t *testing.T) {
+	owners := []*Wallet{NewWallet(KeyTypeP256), NewWallet(KeyTypeP256), NewWallet(KeyTypeP256)}
+	bc := newTestBlockchain(t, owners[0])
+
+	// Every owner knows the redeem script, but only their own key
+	pubKeys := [][]byte{}
+	for _, owner := range owners {
+		pubKeys = append(pubKeys, owner.PublicKey)
+	}
+	ownerWallets := []*Wallets{}
+	address := ""
+	for _, owner := range owners {
+		wallets := walletsWithKey(t, owner)
+		address = wallets.AddMultisig(2, pubKeys)
+		ownerWallets = append(ownerWallets, wallets)
+	}
+
+	funding := &Transaction{nil, []TXInput{{genesisCoinbase(bc).ID, 0, nil, 0}}, []TXOutput{*NewTXOutput(subsidy, address)}, 0, nil}
+	bc.SignTransaction(funding, NewSigner(owners[0].PrivateKey))
+	funding.ID = funding.Hash()
+	mineTestBlock(bc, NewCoinbaseTX(string(owners[0].GetAddress()), ""), funding)
+
+	tx := &Transaction{nil, []TXInput{{funding.ID, 0, nil, 0}}, []TXOutput{*NewTXOutput(subsidy, string(owners[2].GetAddress()))}, 0, nil}
+	ptx := NewPartialTransaction(tx, bc.findPrevTransactions(tx))
+
+	// The owners sign in turn, passing the serialized transaction on
+	for i, wallets := range ownerWallets[:2] {
+		ptx = DeserializePartialTransaction(ptx.Serialize())
+		signed, err := ptx.Sign(wallets, SigHashAll, SigTypeECDSA)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, signed)
+		assert.Equal(t, i == 1, ptx.IsInputSigned(0))
+
+		final, err := ptx.Finalize()
+		if i == 0 {
+			assert.NotNil(t, err, "One signature out of two")
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Nil(t, bc.VerifyTransactions([]*Transaction{final}))
+		mineTestBlock(bc, NewCoinbaseTX(string(owners[2].GetAddress()), ""), final)
+	}
+}
@@ -10,10 +10,10 @@ import (
 
 var (
 	maxNonce = math.MaxInt64
+	// Difficulty, lowered by tests that mine blocks
+	targetBits = 24
 )
 
-const targetBits = 24
-
 type ProofOfWork struct {
 	block  *Block
 	target *big.Int
@@ -31,6 +31,8 @@ func (pow *ProofOfWork) prepareData(nonce int) []byte {
 		[][]byte{
 			pow.block.PrevBlockHash,
 			pow.block.HashTransactions(),
+			pow.block.WitnessCommitment,
+			IntToHex(int64(pow.block.Height)),
 			IntToHex(pow.block.Timestamp),
 			IntToHex(int64(targetBits)),
 			IntToHex(int64(nonce))},
@@ -62,11 +64,13 @@ func (pow *ProofOfWork) Run() (int, []byte) {
 	return nonce, hash[:]
 }
 
+// Validate checks that the block hash is the hash of its header and meets
+// the target
 func (pow *ProofOfWork) Validate() bool {
 	hashInt := big.Int{}
 	data := pow.prepareData(pow.block.Nonce)
 	hash := sha256.Sum256(data)
 	hashInt.SetBytes(hash[:])
 
-	return hashInt.Cmp(pow.target) == -1
+	return bytes.Equal(hash[:], pow.block.Hash) && hashInt.Cmp(pow.target) == -1
 }
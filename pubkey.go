@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+)
+
+const compressedPubKeyLen = 33
+const uncompressedPubKeyLen = 65
+
+// Wallets made before SEC1 encodings concatenated the P-256 X and Y without
+// padding, so their public keys are up to 64 bytes long
+const legacyPubKeyLen = 64
+
+var errInvalidPubKey = errors.New("Public key is not a valid point")
+
+// Encodes a public key in the 33 byte SEC1 compressed form: the parity
+// of Y followed by X, after its key type
+func encodePubKey(pubKey ecdsa.PublicKey) []byte {
+	return withKeyType(pubKey.Curve, elliptic.MarshalCompressed(pubKey.Curve, pubKey.X, pubKey.Y))
+}
+
+// Encodes a public key in the 65 byte SEC1 uncompressed form: 0x04
+// followed by X and Y, after its key type
+func encodeUncompressedPubKey(pubKey ecdsa.PublicKey) []byte {
+	return withKeyType(pubKey.Curve, elliptic.Marshal(pubKey.Curve, pubKey.X, pubKey.Y))
+}
+
+// Prefixes a SEC1 encoded key with its key type. P-256 keys are left
+// plain, so their addresses are the same as before key types existed.
+// Key types never clash with the SEC1 headers
+func withKeyType(curve elliptic.Curve, sec1 []byte) []byte {
+	keyType := curveKeyType(curve)
+	if keyType == KeyTypeP256 {
+		return sec1
+	}
+
+	return append([]byte{byte(keyType)}, sec1...)
+}
+
+// Encodes a P-256 public key like wallets made before SEC1 encodings, so
+// the outputs locked to their addresses can still be spent
+func encodeLegacyPubKey(pubKey ecdsa.PublicKey) []byte {
+	return append(pubKey.X.Bytes(), pubKey.Y.Bytes()...)
+}
+
+// Checks if an encoded public key has the legacy encoding
+func isLegacyPubKey(data []byte) bool {
+	return len(data) > compressedPubKeyLen+1 && len(data) <= legacyPubKeyLen
+}
+
+// ParsePubKey parses a compressed or uncompressed public key of any key
+// type and checks that it is on the curve. Legacy P-256 keys are accepted
+// too
+func ParsePubKey(data []byte) (*ecdsa.PublicKey, error) {
+	if isLegacyPubKey(data) {
+		return parseLegacyPubKey(data)
+	}
+
+	keyType := KeyTypeP256
+	if len(data) == compressedPubKeyLen+1 || len(data) == uncompressedPubKeyLen+1 {
+		// P-256 keys are never prefixed, so each key has a single encoding
+		keyType = KeyType(data[0])
+		if keyType == KeyTypeP256 || !keyType.IsValid() {
+			return nil, errInvalidPubKey
+		}
+		data = data[1:]
+	}
+
+	return parseSEC1PubKey(keyType.Curve(), data)
+}
+
+// Parses a SEC1 public key on curve
+func parseSEC1PubKey(curve elliptic.Curve, data []byte) (*ecdsa.PublicKey, error) {
+	pubKey := ecdsa.PublicKey{Curve: curve}
+
+	switch {
+	case len(data) == compressedPubKeyLen && (data[0] == 0x02 || data[0] == 0x03):
+		pubKey.X, pubKey.Y = elliptic.UnmarshalCompressed(curve, data)
+	case len(data) == uncompressedPubKeyLen && data[0] == 0x04:
+		pubKey.X, pubKey.Y = elliptic.Unmarshal(curve, data)
+	}
+
+	// Both unmarshal functions return nil for points off the curve
+	if pubKey.X == nil {
+		return nil, errInvalidPubKey
+	}
+
+	return &pubKey, nil
+}
+
+// Parses a legacy P-256 public key. A coordinate with leading zero bytes
+// made the key shorter, so every split leaving both coordinates at most 32
+// bytes long is tried
+func parseLegacyPubKey(data []byte) (*ecdsa.PublicKey, error) {
+	curve := elliptic.P256()
+
+	for xLen := len(data) - 32; xLen <= 32; xLen++ {
+		x := new(big.Int).SetBytes(data[:xLen])
+		y := new(big.Int).SetBytes(data[xLen:])
+
+		if curve.IsOnCurve(x, y) {
+			return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+		}
+	}
+
+	return nil, errInvalidPubKey
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPubKeyEncoding(t *testing.T) {
+	hash := sha256.Sum256([]byte("message"))
+
+	for keyType := range keyTypeNames {
+		// Keep generating until a coordinate has a leading zero byte
+		found := false
+		for i := 0; i < 2000 && !found; i++ {
+			privKey, pubKey := newKeyPair(keyType.Curve())
+			uncompressed := encodeUncompressedPubKey(privKey.PublicKey)
+			sec1 := uncompressed[len(uncompressed)-uncompressedPubKeyLen:]
+
+			prefixLen := 1
+			if keyType == KeyTypeP256 {
+				prefixLen = 0
+			}
+			assert.Equal(t, prefixLen+compressedPubKeyLen, len(pubKey))
+			assert.Equal(t, prefixLen+uncompressedPubKeyLen, len(uncompressed))
+
+			for _, encoded := range [][]byte{pubKey, uncompressed} {
+				parsed, err := ParsePubKey(encoded)
+				assert.Nil(t, err)
+				assert.Equal(t, privKey.X, parsed.X)
+				assert.Equal(t, privKey.Y, parsed.Y)
+				assert.Equal(t, keyType, curveKeyType(parsed.Curve))
+			}
+
+			signature := SignHash(privKey, hash[:])
+			assert.True(t, VerifyHash(pubKey, hash[:], signature))
+			assert.True(t, VerifyHash(uncompressed, hash[:], signature))
+
+			found = sec1[1] == 0 || sec1[33] == 0
+		}
+
+		assert.True(t, found, "%s key with a leading zero coordinate byte", keyType)
+	}
+}
+
+func TestParsePubKeyRejectsInvalidKeys(t *testing.T) {
+	privKey, pubKey := newKeyPair(elliptic.P256())
+	uncompressed := encodeUncompressedPubKey(privKey.PublicKey)
+
+	offCurve := append([]byte{}, uncompressed...)
+	offCurve[64] ^= 1
+	wrongPrefix := append([]byte{0x04}, pubKey[1:]...)
+	legacyOffCurve := append([]byte{}, uncompressed[1:]...)
+	legacyOffCurve[63] ^= 1
+	prefixedP256 := append([]byte{byte(KeyTypeP256)}, pubKey...)
+	unknownType := append([]byte{0x7f}, pubKey...)
+
+	for _, data := range [][]byte{offCurve, wrongPrefix, legacyOffCurve, prefixedP256, unknownType, pubKey[:32], nil} {
+		_, err := ParsePubKey(data)
+		assert.Equal(t, errInvalidPubKey, err)
+	}
+}
+
+func TestParseLegacyPubKey(t *testing.T) {
+	keys := []ecdsa.PrivateKey{}
+	for len(keys) < 2 {
+		privKey, _ := newKeyPair(elliptic.P256())
+		// A key with a short coordinate, which shortens the legacy encoding
+		if len(keys) == 0 || privKey.X.BitLen() <= 248 {
+			keys = append(keys, privKey)
+		}
+	}
+
+	for _, privKey := range keys {
+		legacy := encodeLegacyPubKey(privKey.PublicKey)
+		parsed, err := ParsePubKey(legacy)
+		assert.Nil(t, err)
+		assert.Equal(t, privKey.X, parsed.X)
+		assert.Equal(t, privKey.Y, parsed.Y)
+
+		// A legacy wallet signs with the key its address is derived from
+		wallet := newLegacyWallet(privKey)
+		signer, err := wallet.Signer()
+		assert.Nil(t, err)
+		assert.Equal(t, legacy, signer.PubKey())
+
+		hash := sha256.Sum256([]byte("message"))
+		assert.True(t, NewVerifier(KeyTypeP256).VerifyHash(signer.PubKey(), hash[:], signer.Sign(hash[:])))
+	}
+}
+
+func TestVerifierKeyTypes(t *testing.T) {
+	hash := sha256.Sum256([]byte("message"))
+
+	for keyType := range keyTypeNames {
+		signer := NewSigner(NewWallet(keyType).PrivateKey)
+		signature := signer.Sign(hash[:])
+
+		for other := range keyTypeNames {
+			accepted := NewVerifier(other).VerifyHash(signer.PubKey(), hash[:], signature)
+			assert.Equal(t, keyType == other, accepted, "%s signature checked as %s", keyType, other)
+		}
+	}
+}
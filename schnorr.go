@@ -0,0 +1,300 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// SigType is the scheme of a signature. Schnorr signatures end with their
+// type, ECDSA signatures carry no tag like before signature types existed
+type SigType byte
+
+const (
+	SigTypeECDSA SigType = iota
+	SigTypeSchnorr
+)
+
+const schnorrSignatureLen = 64
+
+var sigTypeNames = map[SigType]string{
+	SigTypeECDSA:   "ecdsa",
+	SigTypeSchnorr: "schnorr",
+}
+
+// ParseSigType parses a signature type name like "schnorr"
+func ParseSigType(name string) (SigType, error) {
+	for sigType, sigTypeName := range sigTypeNames {
+		if strings.EqualFold(name, sigTypeName) {
+			return sigType, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown signature type '%s'", name)
+}
+
+func (t SigType) String() string {
+	if name, ok := sigTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("SigType(%d)", byte(t))
+}
+
+// Splits a signature made by a Signer into the signature and its type
+func splitSigType(signature []byte) ([]byte, SigType) {
+	if len(signature) == schnorrSignatureLen+1 && SigType(signature[schnorrSignatureLen]) == SigTypeSchnorr {
+		return signature[:schnorrSignatureLen], SigTypeSchnorr
+	}
+
+	return signature, SigTypeECDSA
+}
+
+// schnorrSigner is a Signer making BIP-340 Schnorr signatures
+type schnorrSigner struct {
+	privKey ecdsa.PrivateKey
+}
+
+// NewSchnorrSigner returns the Schnorr Signer of a secp256k1 private key
+func NewSchnorrSigner(privKey ecdsa.PrivateKey) (Signer, error) {
+	if curveKeyType(privKey.Curve) != KeyTypeSecp256k1 {
+		return nil, errors.New("Schnorr signatures need a secp256k1 key")
+	}
+
+	return schnorrSigner{privKey}, nil
+}
+
+func (s schnorrSigner) PubKey() []byte {
+	return encodePubKey(s.privKey.PublicKey)
+}
+
+func (s schnorrSigner) Sign(hash []byte) []byte {
+	auxRand := make([]byte, 32)
+	_, err := rand.Read(auxRand)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return append(SchnorrSign(s.privKey, hash, auxRand), byte(SigTypeSchnorr))
+}
+
+// Returns the BIP-340 tagged hash of data
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	hasher := sha256.New()
+	hasher.Write(tagHash[:])
+	hasher.Write(tagHash[:])
+	for _, d := range data {
+		hasher.Write(d)
+	}
+
+	return hasher.Sum(nil)
+}
+
+// Returns the 32 byte big endian encoding of x
+func bytes32(x *big.Int) []byte {
+	return x.FillBytes(make([]byte, 32))
+}
+
+// Returns the point with x coordinate x and an even y, if there is one
+func liftX(x *big.Int) (*big.Int, *big.Int, bool) {
+	p := secp256k1.params.P
+	if x.Cmp(p) >= 0 {
+		return nil, nil, false
+	}
+
+	y := new(big.Int).ModSqrt(secp256k1.polynomial(x), p)
+	if y == nil {
+		return nil, nil, false
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(p, y)
+	}
+
+	return x, y, true
+}
+
+// Returns the BIP-340 challenge of a signature with nonce point x rx
+func schnorrChallenge(rx, px *big.Int, hash []byte) *big.Int {
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", bytes32(rx), bytes32(px), hash))
+
+	return e.Mod(e, secp256k1.params.N)
+}
+
+// SchnorrSign makes a BIP-340 signature of a 32 byte hash with a secp256k1
+// private key, auxRand being 32 bytes of fresh randomness
+func SchnorrSign(privKey ecdsa.PrivateKey, hash, auxRand []byte) []byte {
+	curve := secp256k1
+	n := curve.params.N
+
+	// The public key is used as x-only, with an even y
+	d := new(big.Int).Set(privKey.D)
+	if privKey.Y.Bit(0) == 1 {
+		d.Sub(n, d)
+	}
+
+	t := bytes32(d)
+	for i, b := range taggedHash("BIP0340/aux", auxRand) {
+		t[i] ^= b
+	}
+
+	k := new(big.Int).SetBytes(taggedHash("BIP0340/nonce", t, bytes32(privKey.X), hash))
+	k.Mod(k, n)
+	if k.Sign() == 0 {
+		log.Panic("ERROR: Schnorr nonce is zero")
+	}
+
+	rx, ry := curve.ScalarBaseMult(bytes32(k))
+	if ry.Bit(0) == 1 {
+		k.Sub(n, k)
+	}
+
+	e := schnorrChallenge(rx, privKey.X, hash)
+	s := e.Mul(e, d)
+	s.Add(s, k)
+	s.Mod(s, n)
+
+	return append(bytes32(rx), bytes32(s)...)
+}
+
+// SchnorrVerify checks a BIP-340 signature of a hash against an x-only
+// public key
+func SchnorrVerify(pubKeyX, hash, signature []byte) bool {
+	if len(pubKeyX) != 32 {
+		return false
+	}
+
+	px, py, ok := liftX(new(big.Int).SetBytes(pubKeyX))
+	if !ok {
+		return false
+	}
+
+	return verifySchnorr(&ecdsa.PublicKey{Curve: secp256k1, X: px, Y: py}, hash, signature)
+}
+
+// Checks a Schnorr signature against the x coordinate of a public key
+func verifySchnorr(pubKey *ecdsa.PublicKey, hash, signature []byte) bool {
+	rx, s, ok := parseSchnorrSignature(signature)
+	if !ok || curveKeyType(pubKey.Curve) != KeyTypeSecp256k1 {
+		return false
+	}
+
+	curve := secp256k1
+	px, py, _ := liftX(pubKey.X)
+	e := schnorrChallenge(rx, px, hash)
+
+	// R = sG - eP
+	sx, sy := curve.ScalarBaseMult(bytes32(s))
+	ex, ey := curve.ScalarMult(px, py, bytes32(e))
+	if ey.Sign() != 0 {
+		ey.Sub(curve.params.P, ey)
+	}
+	x, y := curve.Add(sx, sy, ex, ey)
+
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return false
+	}
+
+	return y.Bit(0) == 0 && x.Cmp(rx) == 0
+}
+
+// Parses r and s of a Schnorr signature, checking their ranges
+func parseSchnorrSignature(signature []byte) (*big.Int, *big.Int, bool) {
+	if len(signature) != schnorrSignatureLen {
+		return nil, nil, false
+	}
+
+	rx := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if rx.Cmp(secp256k1.params.P) >= 0 || s.Cmp(secp256k1.params.N) >= 0 {
+		return nil, nil, false
+	}
+
+	return rx, s, true
+}
+
+// SchnorrBatch collects Schnorr signatures to verify them all at once,
+// which is faster than one by one. It is safe for concurrent use
+type SchnorrBatch struct {
+	mutex   sync.Mutex
+	entries []schnorrBatchEntry
+}
+
+type schnorrBatchEntry struct {
+	pubKey    *ecdsa.PublicKey
+	hash      []byte
+	signature []byte
+}
+
+// NewSchnorrBatch creates an empty SchnorrBatch
+func NewSchnorrBatch() *SchnorrBatch {
+	return &SchnorrBatch{}
+}
+
+// Add queues a signature of hash by pubKey
+func (b *SchnorrBatch) Add(pubKey *ecdsa.PublicKey, hash, signature []byte) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.entries = append(b.entries, schnorrBatchEntry{pubKey, hash, signature})
+}
+
+// Verify checks that every queued signature is valid. Each equation
+// sG = R + eP is weighted by a random factor before they are summed, so
+// invalid signatures can't cancel each other out
+func (b *SchnorrBatch) Verify() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	curve := secp256k1
+	n := curve.params.N
+	sum := new(big.Int)
+	points := [][2]*big.Int{}
+	scalars := []*big.Int{}
+
+	for i, entry := range b.entries {
+		rx, s, ok := parseSchnorrSignature(entry.signature)
+		if !ok || curveKeyType(entry.pubKey.Curve) != KeyTypeSecp256k1 {
+			return false
+		}
+		px, py, _ := liftX(entry.pubKey.X)
+		rx, ry, ok := liftX(rx)
+		if !ok {
+			return false
+		}
+
+		a := big.NewInt(1)
+		if i > 0 {
+			var err error
+			a, err = rand.Int(rand.Reader, new(big.Int).Sub(n, big.NewInt(1)))
+			if err != nil {
+				log.Panic(err)
+			}
+			a.Add(a, big.NewInt(1))
+		}
+
+		// Accumulate a·s on the left and a·R + a·e·P on the right
+		as := new(big.Int).Mul(a, s)
+		sum.Add(sum, as)
+		sum.Mod(sum, n)
+
+		ae := schnorrChallenge(rx, px, entry.hash)
+		ae.Mul(ae, a)
+		ae.Mod(ae, n)
+
+		points = append(points, [2]*big.Int{rx, ry}, [2]*big.Int{px, py})
+		scalars = append(scalars, a, ae)
+	}
+
+	lhsX, lhsY := curve.ScalarBaseMult(bytes32(sum))
+	rhsX, rhsY := curve.multiScalarMult(points, scalars)
+
+	return lhsX.Cmp(rhsX) == 0 && lhsY.Cmp(rhsY) == 0
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeHex(s string) []byte {
+	data, err := hex.DecodeString(strings.ToLower(s))
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+
+// Vectors from BIP-340
+func TestSchnorrVectors(t *testing.T) {
+	tests := []struct {
+		secretKey, pubKey, auxRand, message, signature string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000003",
+			"F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		},
+		{
+			"B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+			"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			"6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		},
+		{
+			"C90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B14E5C9",
+			"DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8",
+			"C87AA53824B4D7AE2EB035A2B5BBBCCC080E76CDC6D1692C4B0B62D798E6D906",
+			"7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+			"5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7",
+		},
+		{
+			"0B432B2677937381AEF05BB02A66ECD012773062CF3FA2549E44F58ED2401710",
+			"25D1DFF95105F5253C4022F628A996AD3A0D95FBF21D468A1B33F8C160D8F517",
+			"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+			"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+			"7EB0509757E246F19449885651611CB965ECC1A187DD51B64FDA1EDC9637D5EC97582B9CB13DB3933705B32BA982AF5AF25FD78881EBB32771FC5922EFC66EA3",
+		},
+	}
+
+	for i, test := range tests {
+		privKey := newPrivateKey(Secp256k1(), decodeHex(test.secretKey))
+		signature := SchnorrSign(privKey, decodeHex(test.message), decodeHex(test.auxRand))
+
+		assert.Equal(t, test.pubKey, strings.ToUpper(hex.EncodeToString(bytes32(privKey.X))), "Public key %d", i)
+		assert.Equal(t, test.signature, strings.ToUpper(hex.EncodeToString(signature)), "Signature %d", i)
+		assert.True(t, SchnorrVerify(decodeHex(test.pubKey), decodeHex(test.message), signature), "Vector %d verifies", i)
+	}
+
+	// Public key not on the curve
+	assert.False(t, SchnorrVerify(
+		decodeHex("EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34"),
+		decodeHex("243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89"),
+		decodeHex("6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B"),
+	))
+
+	// s equal to the curve order
+	signature := decodeHex(tests[1].signature)
+	copy(signature[32:], bytes32(Secp256k1().Params().N))
+	assert.False(t, SchnorrVerify(decodeHex(tests[1].pubKey), decodeHex(tests[1].message), signature))
+}
+
+func TestSchnorrBatch(t *testing.T) {
+	batch := NewSchnorrBatch()
+	assert.True(t, batch.Verify(), "Empty batch is valid")
+
+	signatures := [][]byte{}
+	for i := 0; i < 5; i++ {
+		privKey, _ := newKeyPair(Secp256k1())
+		hash := sha256.Sum256([]byte{byte(i)})
+		signature := SchnorrSign(privKey, hash[:], make([]byte, 32))
+
+		batch.Add(&privKey.PublicKey, hash[:], signature)
+		signatures = append(signatures, signature)
+	}
+	assert.True(t, batch.Verify())
+
+	// Any broken signature fails the whole batch, which holds the same slices
+	signatures[3][63] ^= 1
+	assert.False(t, batch.Verify())
+}
+
+func TestMuSig(t *testing.T) {
+	privKeys := []*Wallet{}
+	pubKeys := [][]byte{}
+	for i := 0; i < 3; i++ {
+		wallet := NewWallet(KeyTypeSecp256k1)
+		privKeys = append(privKeys, wallet)
+		pubKeys = append(pubKeys, wallet.PublicKey)
+	}
+
+	aggregate, err := AggregatePubKeys(pubKeys)
+	assert.Nil(t, err)
+	_, err = AggregatePubKeys([][]byte{NewWallet(KeyTypeP256).PublicKey})
+	assert.NotNil(t, err, "Only secp256k1 keys can be aggregated")
+
+	// Spend an output locked to the aggregate key like to any other key
+	prevTx := Transaction{nil, []TXInput{}, []TXOutput{{10, NewP2PKHScript(HashPubKey(aggregate))}}, 0, nil}
+	prevTx.ID = prevTx.Hash()
+	tx := Transaction{nil, []TXInput{{prevTx.ID, 0, nil, 0}}, []TXOutput{{10, NewP2PKHScript(HashPubKey(pubKeys[0]))}}, 0, nil}
+	hash := tx.SignatureHash(NewSigHashes(&tx), 0, prevTx.Vout[0], SigHashAll)
+
+	nonces := []*MuSigNonce{}
+	publicNonces := [][]byte{}
+	for range privKeys {
+		nonce := NewMuSigNonce()
+		nonces = append(nonces, nonce)
+		publicNonces = append(publicNonces, nonce.Public())
+	}
+
+	session, err := NewMuSigSession(pubKeys, publicNonces, hash)
+	assert.Nil(t, err)
+	assert.Equal(t, aggregate, session.AggregatePubKey())
+
+	partialSigs := [][]byte{}
+	for i, wallet := range privKeys {
+		partial, err := session.PartialSign(wallet.PrivateKey, nonces[i])
+		assert.Nil(t, err)
+		partialSigs = append(partialSigs, partial)
+	}
+
+	signature := session.Aggregate(partialSigs)
+	parsed, _ := ParsePubKey(aggregate)
+	assert.True(t, SchnorrVerify(bytes32(parsed.X), hash, signature))
+
+	signature = append(signature, byte(SigTypeSchnorr), byte(SigHashAll))
+	tx.SetWitness(0, NewP2PKHScriptSig(signature, aggregate))
+	prevTXs := map[string]Transaction{hex.EncodeToString(prevTx.ID): prevTx}
+	assert.True(t, tx.Verify(prevTXs))
+
+	// A missing partial signature doesn't add up
+	signature = session.Aggregate(partialSigs[1:])
+	assert.False(t, SchnorrVerify(bytes32(parsed.X), hash, signature))
+
+	_, err = session.PartialSign(NewWallet(KeyTypeSecp256k1).PrivateKey, NewMuSigNonce())
+	assert.Equal(t, errMuSigKeyNotFound, err)
+}
+
+func TestSchnorrInputs(t *testing.T) {
+	wallet := NewWallet(KeyTypeSecp256k1)
+	signer, err := wallet.SignerOfType(SigTypeSchnorr)
+	assert.Nil(t, err)
+	_, err = NewWallet(KeyTypeP256).SignerOfType(SigTypeSchnorr)
+	assert.NotNil(t, err, "Schnorr needs a secp256k1 key")
+
+	prevTx := Transaction{nil, []TXInput{}, []TXOutput{{10, NewP2PKHScript(HashPubKey(wallet.PublicKey))}}, 0, nil}
+	prevTx.ID = prevTx.Hash()
+	tx := Transaction{nil, []TXInput{{prevTx.ID, 0, nil, 0}}, []TXOutput{{10, NewP2PKHScript(HashPubKey(wallet.PublicKey))}}, 0, nil}
+	prevTXs := map[string]Transaction{hex.EncodeToString(prevTx.ID): prevTx}
+
+	tx.Sign(signer, prevTXs, SigHashAll)
+	assert.True(t, tx.Verify(prevTXs))
+
+	// The same check deferred to a batch, as for a block
+	checks := newInputChecks(&tx, prevTXs, NewVerifier(KeyTypeSecp256k1))
+	assert.Nil(t, ScriptVerifier{2}.VerifyBatch(checks))
+	assert.NotNil(t, ScriptVerifier{2}.VerifyBatch(newInputChecks(&tx, prevTXs, NewVerifier(KeyTypeP256))), "Chain key type applies to Schnorr signatures")
+
+	// Without its tag the signature is taken for ECDSA and fails
+	witness := tx.InputWitness(0).PushedData()
+	untagged := append(append([]byte{}, witness[0][:schnorrSignatureLen]...), byte(SigHashAll))
+	tx.SetWitness(0, NewP2PKHScriptSig(untagged, witness[1]))
+	assert.False(t, tx.Verify(prevTXs))
+
+	broken := append([]byte{}, witness[0]...)
+	broken[10] ^= 1
+	tx.SetWitness(0, NewP2PKHScriptSig(broken, witness[1]))
+	assert.NotNil(t, ScriptVerifier{2}.VerifyBatch(newInputChecks(&tx, prevTXs, NewVerifier(KeyTypeSecp256k1))))
+}
+
+func BenchmarkSchnorrBatch(b *testing.B) {
+	batch := NewSchnorrBatch()
+	for i := 0; i < 64; i++ {
+		privKey, _ := newKeyPair(Secp256k1())
+		hash := sha256.Sum256([]byte{byte(i)})
+		batch.Add(&privKey.PublicKey, hash[:], SchnorrSign(privKey, hash[:], make([]byte, 32)))
+	}
+
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			batch.Verify()
+		}
+	})
+	b.Run("single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, entry := range batch.entries {
+				verifySchnorr(entry.pubKey, entry.hash, entry.signature)
+			}
+		}
+	})
+}
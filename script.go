@@ -0,0 +1,774 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Opcodes of the script language. Bytes from 0x01 to 0x4b push that
+// many following bytes onto the stack
+const (
+	OP_0              = 0x00
+	OP_PUSHDATA1      = 0x4c
+	OP_PUSHDATA2      = 0x4d
+	OP_1              = 0x51
+	OP_16             = 0x60
+	OP_NOP            = 0x61
+	OP_IF             = 0x63
+	OP_NOTIF          = 0x64
+	OP_ELSE           = 0x67
+	OP_ENDIF          = 0x68
+	OP_VERIFY         = 0x69
+	OP_RETURN         = 0x6a
+	OP_DROP           = 0x75
+	OP_DUP            = 0x76
+	OP_SWAP           = 0x7c
+	OP_SIZE           = 0x82
+	OP_EQUAL          = 0x87
+	OP_EQUALVERIFY    = 0x88
+	OP_SHA256         = 0xa8
+	OP_HASH160        = 0xa9
+	OP_CHECKSIG       = 0xac
+	OP_CHECKSIGVERIFY = 0xad
+	// Stack: sig_1 ... sig_m m pubkey_1 ... pubkey_n n
+	OP_CHECKMULTISIG       = 0xae
+	OP_CHECKMULTISIGVERIFY = 0xaf
+	// Fail unless the transaction lock time, or the input sequence, is at
+	// least the number on top of the stack, which is left in place
+	OP_CHECKLOCKTIMEVERIFY = 0xb1
+	OP_CHECKSEQUENCEVERIFY = 0xb2
+)
+
+var opcodeNames = map[byte]string{
+	OP_0:                   "OP_0",
+	OP_PUSHDATA1:           "OP_PUSHDATA1",
+	OP_PUSHDATA2:           "OP_PUSHDATA2",
+	OP_NOP:                 "OP_NOP",
+	OP_IF:                  "OP_IF",
+	OP_NOTIF:               "OP_NOTIF",
+	OP_ELSE:                "OP_ELSE",
+	OP_ENDIF:               "OP_ENDIF",
+	OP_VERIFY:              "OP_VERIFY",
+	OP_RETURN:              "OP_RETURN",
+	OP_DROP:                "OP_DROP",
+	OP_DUP:                 "OP_DUP",
+	OP_SWAP:                "OP_SWAP",
+	OP_SIZE:                "OP_SIZE",
+	OP_EQUAL:               "OP_EQUAL",
+	OP_EQUALVERIFY:         "OP_EQUALVERIFY",
+	OP_SHA256:              "OP_SHA256",
+	OP_HASH160:             "OP_HASH160",
+	OP_CHECKSIG:            "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY:      "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG:       "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY",
+	OP_CHECKSEQUENCEVERIFY: "OP_CHECKSEQUENCEVERIFY",
+}
+
+// Limits protecting the interpreter from huge scripts
+const (
+	maxScriptSize      = 10000
+	maxScriptElement   = 520
+	maxScriptStackSize = 1000
+	maxMultisigKeys    = 16
+)
+
+var (
+	errScriptFalse     = errors.New("Script evaluated to false")
+	errScriptVerify    = errors.New("Script verification failed")
+	errScriptStack     = errors.New("Script stack has too few items")
+	errScriptMalformed = errors.New("Script is malformed")
+	errScriptLocked    = errors.New("Script lock time has not elapsed")
+)
+
+// Script is a program locking or unlocking an output
+type Script []byte
+
+// scriptOp is a single parsed instruction of a Script
+type scriptOp struct {
+	opcode byte
+	data   []byte
+}
+
+// SignatureChecker checks signatures and lock times found by the
+// signature and timelock opcodes
+type SignatureChecker interface {
+	CheckSig(signature, pubKey []byte) bool
+	CheckLockTime(lockTime int) bool
+	CheckSequence(sequence int) bool
+}
+
+// ScriptBuilder builds scripts one instruction at a time
+type ScriptBuilder struct {
+	script Script
+}
+
+// NewScriptBuilder creates an empty ScriptBuilder
+func NewScriptBuilder() *ScriptBuilder {
+	return &ScriptBuilder{Script{}}
+}
+
+// AddOp appends an opcode
+func (b *ScriptBuilder) AddOp(opcode byte) *ScriptBuilder {
+	b.script = append(b.script, opcode)
+	return b
+}
+
+// AddInt appends an opcode pushing a number from 0 to 16
+func (b *ScriptBuilder) AddInt(n int) *ScriptBuilder {
+	if n == 0 {
+		return b.AddOp(OP_0)
+	}
+	return b.AddOp(byte(OP_1 + n - 1))
+}
+
+// AddNum appends the instruction pushing a non-negative number
+func (b *ScriptBuilder) AddNum(n int) *ScriptBuilder {
+	if n <= 16 {
+		return b.AddInt(n)
+	}
+	return b.AddData(encodeScriptNum(n))
+}
+
+// AddData appends the instruction pushing data onto the stack
+func (b *ScriptBuilder) AddData(data []byte) *ScriptBuilder {
+	switch {
+	case len(data) == 0:
+		b.script = append(b.script, OP_0)
+	case len(data) < OP_PUSHDATA1:
+		b.script = append(b.script, byte(len(data)))
+	case len(data) <= 0xff:
+		b.script = append(b.script, OP_PUSHDATA1, byte(len(data)))
+	default:
+		size := make([]byte, 2)
+		binary.LittleEndian.PutUint16(size, uint16(len(data)))
+		b.script = append(b.script, OP_PUSHDATA2)
+		b.script = append(b.script, size...)
+	}
+
+	b.script = append(b.script, data...)
+	return b
+}
+
+// Script returns the built script
+func (b *ScriptBuilder) Script() Script {
+	return b.script
+}
+
+// NewP2PKHScript creates a script locking an output to a public key hash
+func NewP2PKHScript(pubKeyHash []byte) Script {
+	return NewScriptBuilder().
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(pubKeyHash).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// NewP2PKHScriptSig creates a script unlocking a P2PKH output
+func NewP2PKHScriptSig(signature, pubKey []byte) Script {
+	return NewScriptBuilder().AddData(signature).AddData(pubKey).Script()
+}
+
+// PubKeyHash returns the public key hash of a P2PKH script, or nil
+func (s Script) PubKeyHash() []byte {
+	ops, err := s.parse()
+	if err != nil || len(ops) != 5 {
+		return nil
+	}
+
+	if ops[0].opcode != OP_DUP || ops[1].opcode != OP_HASH160 || len(ops[2].data) != 20 ||
+		ops[3].opcode != OP_EQUALVERIFY || ops[4].opcode != OP_CHECKSIG {
+		return nil
+	}
+
+	return ops[2].data
+}
+
+// NewDataScript creates a provably unspendable script carrying data
+func NewDataScript(data []byte) Script {
+	return NewScriptBuilder().AddOp(OP_RETURN).AddData(data).Script()
+}
+
+// IsUnspendable checks if the script fails before anything else, so its
+// output can never be spent
+func (s Script) IsUnspendable() bool {
+	return len(s) > 0 && s[0] == OP_RETURN
+}
+
+// CarriedData returns the data of a data carrier script, or nil
+func (s Script) CarriedData() []byte {
+	ops, err := s.parse()
+	if err != nil || len(ops) == 0 || ops[0].opcode != OP_RETURN {
+		return nil
+	}
+
+	if len(ops) == 1 {
+		return []byte{}
+	}
+	if len(ops) != 2 || !isPushOp(ops[1].opcode) {
+		return nil
+	}
+
+	return ops[1].pushedValue()
+}
+
+// NewTimelockScript creates a P2PKH script that can't be spent before
+// lockTime. An absolute lock time is a block height or a unix time, a
+// relative one a number of blocks since the output was mined
+func NewTimelockScript(lockTime int, relative bool, pubKeyHash []byte) Script {
+	opcode := byte(OP_CHECKLOCKTIMEVERIFY)
+	if relative {
+		opcode = OP_CHECKSEQUENCEVERIFY
+	}
+
+	script := NewScriptBuilder().AddNum(lockTime).AddOp(opcode).AddOp(OP_DROP).Script()
+
+	return append(script, NewP2PKHScript(pubKeyHash)...)
+}
+
+// Timelock returns the lock time and public key hash of a timelock
+// script. pubKeyHash is nil when the script isn't one
+func (s Script) Timelock() (lockTime int, relative bool, pubKeyHash []byte) {
+	ops, err := s.parse()
+	if err != nil || len(ops) != 8 || ops[2].opcode != OP_DROP {
+		return 0, false, nil
+	}
+
+	if ops[1].opcode != OP_CHECKLOCKTIMEVERIFY && ops[1].opcode != OP_CHECKSEQUENCEVERIFY {
+		return 0, false, nil
+	}
+
+	// The rest of the script has to be P2PKH
+	lockTime, ok := decodeScriptNum(ops[0].pushedValue())
+	pubKeyHash = ops[5].data
+	if !ok || len(pubKeyHash) != 20 || !bytes.HasSuffix(s, NewP2PKHScript(pubKeyHash)) {
+		return 0, false, nil
+	}
+
+	return lockTime, ops[1].opcode == OP_CHECKSEQUENCEVERIFY, pubKeyHash
+}
+
+// NewP2SHScript creates a script locking an output to the hash of a
+// redeem script, which has to be revealed and satisfied to spend it
+func NewP2SHScript(scriptHash []byte) Script {
+	return NewScriptBuilder().
+		AddOp(OP_HASH160).
+		AddData(scriptHash).
+		AddOp(OP_EQUAL).
+		Script()
+}
+
+// ScriptHash returns the redeem script hash of a P2SH script, or nil
+func (s Script) ScriptHash() []byte {
+	ops, err := s.parse()
+	if err != nil || len(ops) != 3 {
+		return nil
+	}
+
+	if ops[0].opcode != OP_HASH160 || len(ops[1].data) != 20 || ops[2].opcode != OP_EQUAL {
+		return nil
+	}
+
+	return ops[1].data
+}
+
+// NewMultisigScript creates a script requiring m signatures by
+// different keys out of pubKeys
+func NewMultisigScript(m int, pubKeys [][]byte) Script {
+	builder := NewScriptBuilder().AddInt(m)
+	for _, pubKey := range pubKeys {
+		builder.AddData(pubKey)
+	}
+
+	return builder.AddInt(len(pubKeys)).AddOp(OP_CHECKMULTISIG).Script()
+}
+
+// MultisigKeys returns the number of required signatures and the public
+// keys of a multisig script. m is 0 when the script isn't one
+func (s Script) MultisigKeys() (int, [][]byte) {
+	ops, err := s.parse()
+	if err != nil || len(ops) < 4 || ops[len(ops)-1].opcode != OP_CHECKMULTISIG {
+		return 0, nil
+	}
+
+	m := smallInt(ops[0].opcode)
+	n := smallInt(ops[len(ops)-2].opcode)
+	if m < 1 || n < m || n != len(ops)-3 {
+		return 0, nil
+	}
+
+	pubKeys := [][]byte{}
+	for _, op := range ops[1 : len(ops)-2] {
+		if op.data == nil {
+			return 0, nil
+		}
+		pubKeys = append(pubKeys, op.data)
+	}
+
+	return m, pubKeys
+}
+
+// PushedData returns the data pushed by a push only script, or nil
+func (s Script) PushedData() [][]byte {
+	ops, err := s.parse()
+	if err != nil {
+		return nil
+	}
+
+	pushes := [][]byte{}
+	for _, op := range ops {
+		if !isPushOp(op.opcode) {
+			return nil
+		}
+		pushes = append(pushes, op.pushedValue())
+	}
+
+	return pushes
+}
+
+// IsPushOnly checks if a script does nothing but push data
+func (s Script) IsPushOnly() bool {
+	ops, err := s.parse()
+	if err != nil {
+		return false
+	}
+
+	for _, op := range ops {
+		if !isPushOp(op.opcode) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String disassembles the script
+func (s Script) String() string {
+	ops, err := s.parse()
+	if err != nil {
+		return fmt.Sprintf("[malformed %x]", []byte(s))
+	}
+
+	words := []string{}
+	for _, op := range ops {
+		switch {
+		case op.data != nil:
+			words = append(words, fmt.Sprintf("%x", op.data))
+		case op.opcode >= OP_1 && op.opcode <= OP_16:
+			words = append(words, fmt.Sprintf("OP_%d", op.opcode-OP_1+1))
+		case opcodeNames[op.opcode] != "":
+			words = append(words, opcodeNames[op.opcode])
+		default:
+			words = append(words, fmt.Sprintf("OP_UNKNOWN%d", op.opcode))
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Splits the script into instructions
+func (s Script) parse() ([]scriptOp, error) {
+	ops := []scriptOp{}
+
+	if len(s) > maxScriptSize {
+		return nil, errScriptMalformed
+	}
+
+	for i := 0; i < len(s); {
+		opcode := s[i]
+		i++
+
+		size := 0
+		switch {
+		case opcode > OP_0 && opcode < OP_PUSHDATA1:
+			size = int(opcode)
+		case opcode == OP_PUSHDATA1:
+			if i+1 > len(s) {
+				return nil, errScriptMalformed
+			}
+			size = int(s[i])
+			i++
+		case opcode == OP_PUSHDATA2:
+			if i+2 > len(s) {
+				return nil, errScriptMalformed
+			}
+			size = int(binary.LittleEndian.Uint16(s[i : i+2]))
+			i += 2
+		default:
+			ops = append(ops, scriptOp{opcode, nil})
+			continue
+		}
+
+		if i+size > len(s) {
+			return nil, errScriptMalformed
+		}
+		ops = append(ops, scriptOp{opcode, append([]byte{}, s[i:i+size]...)})
+		i += size
+	}
+
+	return ops, nil
+}
+
+// Checks if an opcode pushes data or a small number
+func isPushOp(opcode byte) bool {
+	return opcode <= OP_PUSHDATA2 || (opcode >= OP_1 && opcode <= OP_16)
+}
+
+// Returns the number pushed by OP_1 to OP_16, or -1
+func smallInt(opcode byte) int {
+	if opcode < OP_1 || opcode > OP_16 {
+		return -1
+	}
+	return int(opcode-OP_1) + 1
+}
+
+// Returns the value a push instruction puts onto the stack
+func (op scriptOp) pushedValue() []byte {
+	if op.opcode >= OP_1 && op.opcode <= OP_16 {
+		return []byte{op.opcode - OP_1 + 1}
+	}
+	if op.data == nil {
+		return []byte{}
+	}
+	return op.data
+}
+
+// VerifyScript runs the unlocking script followed by the locking script
+// and checks that they leave true on the stack. For P2SH locking scripts
+// the redeem script pushed last by the unlocking script is run as well
+func VerifyScript(scriptSig, scriptPubKey Script, checker SignatureChecker) error {
+	if !scriptSig.IsPushOnly() {
+		return errors.New("Unlocking script must only push data")
+	}
+
+	stack, err := EvalScript(scriptSig, [][]byte{}, checker)
+	if err != nil {
+		return err
+	}
+	unlockStack := append([][]byte{}, stack...)
+
+	stack, err = EvalScript(scriptPubKey, stack, checker)
+	if err != nil {
+		return err
+	}
+
+	if len(stack) == 0 || !castToBool(stack[len(stack)-1]) {
+		return errScriptFalse
+	}
+
+	if scriptPubKey.ScriptHash() == nil {
+		return nil
+	}
+
+	// The hash matched, so the redeem script can be run on what is left
+	redeemScript := Script(unlockStack[len(unlockStack)-1])
+	stack, err = EvalScript(redeemScript, unlockStack[:len(unlockStack)-1], checker)
+	if err != nil {
+		return err
+	}
+
+	if len(stack) == 0 || !castToBool(stack[len(stack)-1]) {
+		return errScriptFalse
+	}
+
+	return nil
+}
+
+// EvalScript executes a script on the stack and returns the resulting stack
+func EvalScript(script Script, stack [][]byte, checker SignatureChecker) ([][]byte, error) {
+	ops, err := script.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	// Each entry tells if the branch of an enclosing IF is being executed
+	conditions := []bool{}
+
+	pop := func() []byte {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return top
+	}
+
+	for _, op := range ops {
+		executing := true
+		for _, condition := range conditions {
+			executing = executing && condition
+		}
+
+		switch op.opcode {
+		case OP_IF, OP_NOTIF:
+			value := false
+			if executing {
+				if len(stack) < 1 {
+					return nil, errScriptStack
+				}
+				value = castToBool(pop())
+				if op.opcode == OP_NOTIF {
+					value = !value
+				}
+			}
+			conditions = append(conditions, value)
+			continue
+		case OP_ELSE:
+			if len(conditions) == 0 {
+				return nil, errScriptMalformed
+			}
+			conditions[len(conditions)-1] = !conditions[len(conditions)-1]
+			continue
+		case OP_ENDIF:
+			if len(conditions) == 0 {
+				return nil, errScriptMalformed
+			}
+			conditions = conditions[:len(conditions)-1]
+			continue
+		}
+
+		if !executing {
+			continue
+		}
+
+		if isPushOp(op.opcode) {
+			value := op.pushedValue()
+			if len(value) > maxScriptElement {
+				return nil, errScriptMalformed
+			}
+			stack = append(stack, value)
+		} else {
+			err := execOp(op.opcode, &stack, pop, checker)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(stack) > maxScriptStackSize {
+			return nil, errors.New("Script stack is too big")
+		}
+	}
+
+	if len(conditions) != 0 {
+		return nil, errScriptMalformed
+	}
+
+	return stack, nil
+}
+
+// Executes an opcode that isn't a push or a flow control
+func execOp(opcode byte, stack *[][]byte, pop func() []byte, checker SignatureChecker) error {
+	need := func(n int) error {
+		if len(*stack) < n {
+			return errScriptStack
+		}
+		return nil
+	}
+	push := func(value []byte) {
+		*stack = append(*stack, value)
+	}
+
+	switch opcode {
+	case OP_NOP:
+	case OP_VERIFY:
+		if err := need(1); err != nil {
+			return err
+		}
+		if !castToBool(pop()) {
+			return errScriptVerify
+		}
+	case OP_RETURN:
+		return errors.New("Script is unspendable")
+	case OP_DROP:
+		if err := need(1); err != nil {
+			return err
+		}
+		pop()
+	case OP_DUP:
+		if err := need(1); err != nil {
+			return err
+		}
+		push((*stack)[len(*stack)-1])
+	case OP_SWAP:
+		if err := need(2); err != nil {
+			return err
+		}
+		a := pop()
+		b := pop()
+		push(a)
+		push(b)
+	case OP_SIZE:
+		if err := need(1); err != nil {
+			return err
+		}
+		push(encodeScriptNum(len((*stack)[len(*stack)-1])))
+	case OP_EQUAL, OP_EQUALVERIFY:
+		if err := need(2); err != nil {
+			return err
+		}
+		equal := bytes.Equal(pop(), pop())
+		if opcode == OP_EQUALVERIFY {
+			if !equal {
+				return errScriptVerify
+			}
+		} else {
+			push(encodeScriptBool(equal))
+		}
+	case OP_SHA256:
+		if err := need(1); err != nil {
+			return err
+		}
+		hash := sha256.Sum256(pop())
+		push(hash[:])
+	case OP_HASH160:
+		if err := need(1); err != nil {
+			return err
+		}
+		push(HashPubKey(pop()))
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		if err := need(2); err != nil {
+			return err
+		}
+		pubKey := pop()
+		signature := pop()
+		valid := checker.CheckSig(signature, pubKey)
+		if opcode == OP_CHECKSIGVERIFY {
+			if !valid {
+				return errScriptVerify
+			}
+		} else {
+			push(encodeScriptBool(valid))
+		}
+	case OP_CHECKLOCKTIMEVERIFY, OP_CHECKSEQUENCEVERIFY:
+		if err := need(1); err != nil {
+			return err
+		}
+		lockTime, ok := decodeScriptNum((*stack)[len(*stack)-1])
+		if !ok {
+			return errors.New("Invalid lock time")
+		}
+		if opcode == OP_CHECKLOCKTIMEVERIFY && !checker.CheckLockTime(lockTime) {
+			return errScriptLocked
+		}
+		if opcode == OP_CHECKSEQUENCEVERIFY && !checker.CheckSequence(lockTime) {
+			return errScriptLocked
+		}
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		valid, err := checkMultisig(stack, pop, checker)
+		if err != nil {
+			return err
+		}
+		if opcode == OP_CHECKMULTISIGVERIFY {
+			if !valid {
+				return errScriptVerify
+			}
+		} else {
+			push(encodeScriptBool(valid))
+		}
+	default:
+		return fmt.Errorf("Unknown opcode 0x%02x", opcode)
+	}
+
+	return nil
+}
+
+// Pops the operands of OP_CHECKMULTISIG and checks that the signatures
+// match the public keys in the same order
+func checkMultisig(stack *[][]byte, pop func() []byte, checker SignatureChecker) (bool, error) {
+	popCount := func(max int) (int, error) {
+		if len(*stack) < 1 {
+			return 0, errScriptStack
+		}
+		count, ok := decodeScriptNum(pop())
+		if !ok || count < 0 || count > max {
+			return 0, errors.New("Invalid multisig key or signature count")
+		}
+		if len(*stack) < count {
+			return 0, errScriptStack
+		}
+		return count, nil
+	}
+
+	n, err := popCount(maxMultisigKeys)
+	if err != nil {
+		return false, err
+	}
+	pubKeys := make([][]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		pubKeys[i] = pop()
+	}
+
+	m, err := popCount(n)
+	if err != nil {
+		return false, err
+	}
+	signatures := make([][]byte, m)
+	for i := m - 1; i >= 0; i-- {
+		signatures[i] = pop()
+	}
+
+	key := 0
+	for _, signature := range signatures {
+		for key < len(pubKeys) && !checker.CheckSig(signature, pubKeys[key]) {
+			key++
+		}
+		if key == len(pubKeys) {
+			return false, nil
+		}
+		key++
+	}
+
+	return true, nil
+}
+
+// Stack items are false when empty or all zeros
+func castToBool(value []byte) bool {
+	for _, b := range value {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeScriptBool(value bool) []byte {
+	if value {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+// Decodes a non-negative little endian stack item of up to 4 bytes
+func decodeScriptNum(value []byte) (int, bool) {
+	if len(value) > 4 || (len(value) > 0 && value[len(value)-1]&0x80 != 0) {
+		return 0, false
+	}
+
+	n := 0
+	for i := len(value) - 1; i >= 0; i-- {
+		n = n<<8 | int(value[i])
+	}
+
+	return n, true
+}
+
+// Encodes a non-negative number as a little endian stack item
+func encodeScriptNum(n int) []byte {
+	result := []byte{}
+
+	for n > 0 {
+		result = append(result, byte(n&0xff))
+		n >>= 8
+	}
+
+	// Keep the top bit clear, it marks negative numbers
+	if len(result) > 0 && result[len(result)-1]&0x80 != 0 {
+		result = append(result, 0)
+	}
+
+	return result
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testChecker accepts signatures equal to the public key they are checked
+// with, and lock times up to its own
+type testChecker struct {
+	lockTime int
+	sequence int
+}
+
+func (c testChecker) CheckSig(signature, pubKey []byte) bool {
+	return bytes.Equal(signature, pubKey)
+}
+
+func (c testChecker) CheckLockTime(lockTime int) bool {
+	return c.lockTime >= lockTime
+}
+
+func (c testChecker) CheckSequence(sequence int) bool {
+	return c.sequence >= sequence
+}
+
+func TestP2PKHScript(t *testing.T) {
+	pubKey := []byte("public key")
+	scriptPubKey := NewP2PKHScript(HashPubKey(pubKey))
+
+	assert.Equal(t, HashPubKey(pubKey), scriptPubKey.PubKeyHash(), "Public key hash is extracted")
+
+	err := VerifyScript(NewP2PKHScriptSig(pubKey, pubKey), scriptPubKey, testChecker{})
+	assert.Nil(t, err, "Valid signature unlocks the output")
+
+	err = VerifyScript(NewP2PKHScriptSig([]byte("bad"), pubKey), scriptPubKey, testChecker{})
+	assert.Equal(t, errScriptFalse, err, "Invalid signature leaves false on the stack")
+
+	other := []byte("other key")
+	err = VerifyScript(NewP2PKHScriptSig(other, other), scriptPubKey, testChecker{})
+	assert.Equal(t, errScriptVerify, err, "Other key fails EQUALVERIFY")
+}
+
+func TestScriptConditionals(t *testing.T) {
+	script := NewScriptBuilder().
+		AddOp(OP_IF).
+		AddData([]byte("yes")).
+		AddOp(OP_ELSE).
+		AddData([]byte("no")).
+		AddOp(OP_ENDIF).
+		Script()
+
+	stack, err := EvalScript(script, [][]byte{{1}}, testChecker{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("yes")}, stack)
+
+	stack, err = EvalScript(script, [][]byte{{}}, testChecker{})
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("no")}, stack)
+
+	_, err = EvalScript(Script{OP_1, OP_IF}, [][]byte{}, testChecker{})
+	assert.Equal(t, errScriptMalformed, err, "Unbalanced IF is rejected")
+}
+
+func TestScriptRejectsNonPushUnlocking(t *testing.T) {
+	err := VerifyScript(Script{OP_1, OP_DUP}, Script{OP_EQUAL}, testChecker{})
+
+	assert.NotNil(t, err)
+}
+
+func TestScriptPushData(t *testing.T) {
+	data := bytes.Repeat([]byte{0xab}, 300)
+	script := NewScriptBuilder().AddData(data).AddInt(0).AddInt(16).Script()
+
+	assert.Equal(t, [][]byte{data, {}, {16}}, script.PushedData())
+
+	_, err := EvalScript(script[:10], [][]byte{}, testChecker{})
+	assert.Equal(t, errScriptMalformed, err, "Truncated push is rejected")
+}
+
+func TestScriptString(t *testing.T) {
+	script := NewP2PKHScript(bytes.Repeat([]byte{0x01}, 20))
+
+	assert.Equal(
+		t,
+		"OP_DUP OP_HASH160 0101010101010101010101010101010101010101 OP_EQUALVERIFY OP_CHECKSIG",
+		script.String(),
+	)
+}
+
+func TestP2SHMultisigScript(t *testing.T) {
+	pubKeys := [][]byte{[]byte("key1"), []byte("key2"), []byte("key3")}
+	redeemScript := NewMultisigScript(2, pubKeys)
+	scriptPubKey := NewP2SHScript(HashPubKey(redeemScript))
+
+	m, keys := redeemScript.MultisigKeys()
+	assert.Equal(t, 2, m)
+	assert.Equal(t, pubKeys, keys)
+	assert.Equal(t, HashPubKey(redeemScript), scriptPubKey.ScriptHash())
+
+	unlock := func(signatures ...[]byte) Script {
+		builder := NewScriptBuilder()
+		for _, signature := range signatures {
+			builder.AddData(signature)
+		}
+		return builder.AddData(redeemScript).Script()
+	}
+
+	err := VerifyScript(unlock(pubKeys[0], pubKeys[2]), scriptPubKey, testChecker{})
+	assert.Nil(t, err, "Two signatures in key order unlock the output")
+
+	err = VerifyScript(unlock(pubKeys[2], pubKeys[0]), scriptPubKey, testChecker{})
+	assert.Equal(t, errScriptFalse, err, "Signatures out of key order are rejected")
+
+	err = VerifyScript(unlock(pubKeys[1]), scriptPubKey, testChecker{})
+	assert.Equal(t, errScriptStack, err, "One signature isn't enough")
+
+	other := NewMultisigScript(1, pubKeys)
+	err = VerifyScript(NewScriptBuilder().AddData(pubKeys[0]).AddData(other).Script(), scriptPubKey, testChecker{})
+	assert.Equal(t, errScriptFalse, err, "Other redeem script doesn't match the hash")
+}
+
+func TestTimelockScript(t *testing.T) {
+	pubKey := []byte("public key")
+	scriptPubKey := NewTimelockScript(1000, false, HashPubKey(pubKey))
+
+	lockTime, relative, pubKeyHash := scriptPubKey.Timelock()
+	assert.Equal(t, 1000, lockTime)
+	assert.False(t, relative)
+	assert.Equal(t, HashPubKey(pubKey), pubKeyHash)
+
+	scriptSig := NewP2PKHScriptSig(pubKey, pubKey)
+	err := VerifyScript(scriptSig, scriptPubKey, testChecker{lockTime: 999})
+	assert.Equal(t, errScriptLocked, err, "Output is locked before the lock time")
+
+	err = VerifyScript(scriptSig, scriptPubKey, testChecker{lockTime: 1000})
+	assert.Nil(t, err, "Output unlocks at the lock time")
+
+	relativeScript := NewTimelockScript(5, true, HashPubKey(pubKey))
+	err = VerifyScript(scriptSig, relativeScript, testChecker{lockTime: 1000, sequence: 4})
+	assert.Equal(t, errScriptLocked, err, "Relative lock needs the input sequence")
+
+	err = VerifyScript(scriptSig, relativeScript, testChecker{sequence: 5})
+	assert.Nil(t, err)
+}
+
+func TestDataScript(t *testing.T) {
+	script := NewDataScript([]byte("document hash"))
+
+	assert.True(t, script.IsUnspendable())
+	assert.Equal(t, []byte("document hash"), script.CarriedData())
+	assert.Nil(t, NewP2PKHScript(bytes.Repeat([]byte{0x01}, 20)).CarriedData())
+
+	_, err := EvalScript(script, [][]byte{}, testChecker{})
+	assert.NotNil(t, err, "Data outputs can't be spent")
+
+	tx := Transaction{Vout: []TXOutput{{0, NewDataScript(make([]byte, maxDataCarrierSize+1))}}}
+	assert.False(t, tx.HasValidDataOutputs(), "Data over the size limit is rejected")
+}
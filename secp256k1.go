@@ -0,0 +1,283 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"math/big"
+)
+
+// secp256k1Curve implements elliptic.Curve for secp256k1, y² = x³ + 7.
+// The generic CurveParams arithmetic assumes a = -3 and can't be used
+type secp256k1Curve struct {
+	params *elliptic.CurveParams
+}
+
+var secp256k1 = newSecp256k1()
+
+// Secp256k1 returns the curve used by Bitcoin
+func Secp256k1() elliptic.Curve {
+	return secp256k1
+}
+
+func newSecp256k1() secp256k1Curve {
+	params := &elliptic.CurveParams{Name: "secp256k1", BitSize: 256}
+	params.P, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F", 16)
+	params.N, _ = new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141", 16)
+	params.B = big.NewInt(7)
+	params.Gx, _ = new(big.Int).SetString("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798", 16)
+	params.Gy, _ = new(big.Int).SetString("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8", 16)
+
+	return secp256k1Curve{params}
+}
+
+func (c secp256k1Curve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+// Returns x³ + 7
+func (c secp256k1Curve) polynomial(x *big.Int) *big.Int {
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+	x3.Add(x3, c.params.B)
+
+	return x3.Mod(x3, c.params.P)
+}
+
+func (c secp256k1Curve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+
+	return y2.Cmp(c.polynomial(x)) == 0
+}
+
+func (c secp256k1Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return c.fromJacobian(c.addJacobian(c.toJacobian(x1, y1), c.toJacobian(x2, y2)))
+}
+
+func (c secp256k1Curve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	return c.fromJacobian(c.doubleJacobian(c.toJacobian(x1, y1)))
+}
+
+func (c secp256k1Curve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+	base := c.toJacobian(x1, y1)
+	result := jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+
+	for _, b := range k {
+		for bit := 7; bit >= 0; bit-- {
+			result = c.doubleJacobian(result)
+			if b>>uint(bit)&1 == 1 {
+				result = c.addJacobian(result, base)
+			}
+		}
+	}
+
+	return c.fromJacobian(result)
+}
+
+// Returns the sum of scalars[i]·points[i]. The doublings are shared by all
+// the points, which makes it faster than separate multiplications
+func (c secp256k1Curve) multiScalarMult(points [][2]*big.Int, scalars []*big.Int) (*big.Int, *big.Int) {
+	bases := []jacobianPoint{}
+	for _, point := range points {
+		bases = append(bases, c.toJacobian(point[0], point[1]))
+	}
+
+	result := jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	for bit := c.params.N.BitLen() - 1; bit >= 0; bit-- {
+		result = c.doubleJacobian(result)
+		for i, scalar := range scalars {
+			if scalar.Bit(bit) == 1 {
+				result = c.addJacobian(result, bases[i])
+			}
+		}
+	}
+
+	return c.fromJacobian(result)
+}
+
+func (c secp256k1Curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.ScalarMult(c.params.Gx, c.params.Gy, k)
+}
+
+// Unmarshal is picked up by elliptic.Unmarshal in place of its generic
+// version
+func (c secp256k1Curve) Unmarshal(data []byte) (*big.Int, *big.Int) {
+	if len(data) != 65 || data[0] != 0x04 {
+		return nil, nil
+	}
+
+	x := new(big.Int).SetBytes(data[1:33])
+	y := new(big.Int).SetBytes(data[33:])
+	if !c.IsOnCurve(x, y) {
+		return nil, nil
+	}
+
+	return x, y
+}
+
+// UnmarshalCompressed is picked up by elliptic.UnmarshalCompressed, whose
+// generic version only works for curves with a = -3
+func (c secp256k1Curve) UnmarshalCompressed(data []byte) (*big.Int, *big.Int) {
+	if len(data) != 33 || (data[0] != 0x02 && data[0] != 0x03) {
+		return nil, nil
+	}
+
+	p := c.params.P
+	x := new(big.Int).SetBytes(data[1:])
+	if x.Cmp(p) >= 0 {
+		return nil, nil
+	}
+
+	y := new(big.Int).ModSqrt(c.polynomial(x), p)
+	if y == nil {
+		return nil, nil
+	}
+	if byte(y.Bit(0)) != data[0]&1 {
+		y.Sub(p, y)
+	}
+
+	return x, y
+}
+
+// jacobianPoint is the point (X/Z², Y/Z³), Z is zero at infinity
+type jacobianPoint struct {
+	x, y, z *big.Int
+}
+
+// Affine (0, 0) stands for the point at infinity, like in crypto/elliptic
+func (c secp256k1Curve) toJacobian(x, y *big.Int) jacobianPoint {
+	z := new(big.Int)
+	if x.Sign() != 0 || y.Sign() != 0 {
+		z.SetInt64(1)
+	}
+
+	return jacobianPoint{new(big.Int).Set(x), new(big.Int).Set(y), z}
+}
+
+func (c secp256k1Curve) fromJacobian(point jacobianPoint) (*big.Int, *big.Int) {
+	if point.z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+
+	p := c.params.P
+	zInv := new(big.Int).ModInverse(point.z, p)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+
+	x := new(big.Int).Mul(point.x, zInv2)
+	x.Mod(x, p)
+	y := zInv2.Mul(zInv2, zInv)
+	y.Mul(y, point.y)
+	y.Mod(y, p)
+
+	return x, y
+}
+
+// Doubles a point with the dbl-2009-l formulas for a = 0
+func (c secp256k1Curve) doubleJacobian(point jacobianPoint) jacobianPoint {
+	p := c.params.P
+	if point.z.Sign() == 0 || point.y.Sign() == 0 {
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+
+	a := new(big.Int).Mul(point.x, point.x)
+	a.Mod(a, p)
+	b := new(big.Int).Mul(point.y, point.y)
+	b.Mod(b, p)
+	cc := new(big.Int).Mul(b, b)
+	cc.Mod(cc, p)
+
+	// d = 2((x + b)² - a - cc)
+	d := new(big.Int).Add(point.x, b)
+	d.Mul(d, d)
+	d.Sub(d, a)
+	d.Sub(d, cc)
+	d.Lsh(d, 1)
+	d.Mod(d, p)
+
+	e := new(big.Int).Mul(a, big.NewInt(3))
+	f := new(big.Int).Mul(e, e)
+
+	x3 := new(big.Int).Sub(f, new(big.Int).Lsh(d, 1))
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(d, x3)
+	y3.Mul(y3, e)
+	y3.Sub(y3, new(big.Int).Lsh(cc, 3))
+	y3.Mod(y3, p)
+
+	z3 := new(big.Int).Mul(point.y, point.z)
+	z3.Lsh(z3, 1)
+	z3.Mod(z3, p)
+
+	return jacobianPoint{x3, y3, z3}
+}
+
+// Adds two points with the add-2007-bl formulas
+func (c secp256k1Curve) addJacobian(p1, p2 jacobianPoint) jacobianPoint {
+	p := c.params.P
+	if p1.z.Sign() == 0 {
+		return p2
+	}
+	if p2.z.Sign() == 0 {
+		return p1
+	}
+
+	z1z1 := new(big.Int).Mul(p1.z, p1.z)
+	z1z1.Mod(z1z1, p)
+	z2z2 := new(big.Int).Mul(p2.z, p2.z)
+	z2z2.Mod(z2z2, p)
+
+	u1 := new(big.Int).Mul(p1.x, z2z2)
+	u1.Mod(u1, p)
+	u2 := new(big.Int).Mul(p2.x, z1z1)
+	u2.Mod(u2, p)
+
+	s1 := new(big.Int).Mul(p1.y, p2.z)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, p)
+	s2 := new(big.Int).Mul(p2.y, p1.z)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, p)
+
+	h := new(big.Int).Sub(u2, u1)
+	h.Mod(h, p)
+	r := new(big.Int).Sub(s2, s1)
+	r.Mod(r, p)
+
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return c.doubleJacobian(p1)
+		}
+		return jacobianPoint{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	r.Lsh(r, 1)
+
+	i := new(big.Int).Lsh(h, 1)
+	i.Mul(i, i)
+	i.Mod(i, p)
+	j := new(big.Int).Mul(h, i)
+	v := new(big.Int).Mul(u1, i)
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, j)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	x3.Mod(x3, p)
+
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	y3.Sub(y3, new(big.Int).Lsh(s1.Mul(s1, j), 1))
+	y3.Mod(y3, p)
+
+	z3 := new(big.Int).Add(p1.z, p2.z)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, z1z1)
+	z3.Sub(z3, z2z2)
+	z3.Mul(z3, h)
+	z3.Mod(z3, p)
+
+	return jacobianPoint{x3, y3, z3}
+}
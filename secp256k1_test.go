@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecp256k1ScalarBaseMult(t *testing.T) {
+	curve := Secp256k1()
+	params := curve.Params()
+
+	tests := []struct {
+		k    int64
+		x, y string
+	}{
+		{1, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", "483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"},
+		{2, "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5", "1ae168fea63dc339a3c58419466ceaeef7f632653266d0e1236431a950cfe52a"},
+		{3, "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9", "388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672"},
+	}
+
+	for _, test := range tests {
+		x, y := curve.ScalarBaseMult(big.NewInt(test.k).Bytes())
+		assert.Equal(t, test.x, hex.EncodeToString(x.FillBytes(make([]byte, 32))), "x of %dG", test.k)
+		assert.Equal(t, test.y, hex.EncodeToString(y.FillBytes(make([]byte, 32))), "y of %dG", test.k)
+		assert.True(t, curve.IsOnCurve(x, y))
+	}
+
+	// (n - 1)G is -G
+	x, y := curve.ScalarBaseMult(new(big.Int).Sub(params.N, big.NewInt(1)).Bytes())
+	assert.Equal(t, params.Gx, x)
+	assert.Equal(t, new(big.Int).Sub(params.P, params.Gy), y)
+
+	x, y = curve.ScalarBaseMult(params.N.Bytes())
+	assert.Equal(t, 0, x.Sign(), "nG is the point at infinity")
+	assert.Equal(t, 0, y.Sign())
+
+	x2, y2 := curve.Double(params.Gx, params.Gy)
+	x3, y3 := curve.Add(x2, y2, params.Gx, params.Gy)
+	assert.Equal(t, tests[2].x, hex.EncodeToString(x3.Bytes()))
+	assert.Equal(t, tests[2].y, hex.EncodeToString(y3.Bytes()))
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// SigHashType selects the parts of a transaction a signature commits to.
+// It is appended to every signature as its last byte
+type SigHashType byte
+
+const (
+	// SigHashAll signs all inputs and outputs
+	SigHashAll SigHashType = 0x01
+	// SigHashNone signs the inputs only, anyone can change the outputs
+	SigHashNone SigHashType = 0x02
+	// SigHashSingle signs the inputs and the output with the same index
+	SigHashSingle SigHashType = 0x03
+	// SigHashAnyoneCanPay only signs its own input, so others can be added
+	SigHashAnyoneCanPay SigHashType = 0x80
+)
+
+var sigHashNames = map[SigHashType]string{
+	SigHashAll:    "ALL",
+	SigHashNone:   "NONE",
+	SigHashSingle: "SINGLE",
+}
+
+// ParseSigHashType parses names like ALL or SINGLE|ANYONECANPAY
+func ParseSigHashType(name string) (SigHashType, error) {
+	parts := strings.Split(strings.ToUpper(name), "|")
+
+	hashType := SigHashType(0)
+	for base, baseName := range sigHashNames {
+		if parts[0] == baseName {
+			hashType = base
+		}
+	}
+
+	if hashType == 0 || len(parts) > 2 || (len(parts) == 2 && parts[1] != "ANYONECANPAY") {
+		return 0, fmt.Errorf("Unknown signature hash type '%s'", name)
+	}
+	if len(parts) == 2 {
+		hashType |= SigHashAnyoneCanPay
+	}
+
+	return hashType, nil
+}
+
+// IsValid checks if the hash type is one of the known combinations
+func (t SigHashType) IsValid() bool {
+	_, ok := sigHashNames[t.base()]
+	return ok && t&^(SigHashAnyoneCanPay|0x03) == 0
+}
+
+func (t SigHashType) String() string {
+	name := sigHashNames[t.base()]
+	if t&SigHashAnyoneCanPay != 0 {
+		name += "|ANYONECANPAY"
+	}
+
+	return name
+}
+
+// Returns the hash type without the ANYONECANPAY flag
+func (t SigHashType) base() SigHashType {
+	return t &^ SigHashAnyoneCanPay
+}
+
+// SigHashes holds the parts of the signature digest shared by all inputs
+// of a transaction, so they are hashed once instead of once per input
+type SigHashes struct {
+	Prevouts  []byte
+	Sequences []byte
+	Outputs   []byte
+}
+
+// NewSigHashes hashes the outpoints, sequences and outputs of tx. It has
+// to be recomputed when any of them changes
+func NewSigHashes(tx *Transaction) *SigHashes {
+	prevouts := sha256.New()
+	sequences := sha256.New()
+	for _, vin := range tx.Vin {
+		writeOutpoint(prevouts, vin)
+		writeSigHashInt(sequences, vin.Sequence)
+	}
+
+	outputs := sha256.New()
+	for _, vout := range tx.Vout {
+		writeOutput(outputs, vout)
+	}
+
+	return &SigHashes{prevouts.Sum(nil), sequences.Sum(nil), outputs.Sum(nil)}
+}
+
+// SignatureHash returns the data signed by an input. It commits to the
+// output spent by the input, including its value, and to the parts of the
+// transaction selected by hashType. It is nil when SIGHASH_SINGLE has no
+// matching output
+func (tx *Transaction) SignatureHash(hashes *SigHashes, index int, prevOut TXOutput, hashType SigHashType) []byte {
+	zero := make([]byte, sha256.Size)
+	prevouts, sequences, outputs := hashes.Prevouts, hashes.Sequences, hashes.Outputs
+
+	// Other inputs can be added, or update their relative lock times when
+	// the outputs aren't all signed
+	if hashType&SigHashAnyoneCanPay != 0 {
+		prevouts = zero
+	}
+	if hashType&SigHashAnyoneCanPay != 0 || hashType.base() != SigHashAll {
+		sequences = zero
+	}
+
+	switch hashType.base() {
+	case SigHashNone:
+		outputs = zero
+	case SigHashSingle:
+		if index >= len(tx.Vout) {
+			return nil
+		}
+
+		h := sha256.New()
+		writeOutput(h, tx.Vout[index])
+		outputs = h.Sum(nil)
+	}
+
+	vin := tx.Vin[index]
+	h := sha256.New()
+	writeSigHashInt(h, int(hashType))
+	h.Write(prevouts)
+	h.Write(sequences)
+	writeOutpoint(h, vin)
+	writeSigHashBytes(h, prevOut.ScriptPubKey)
+	writeSigHashInt(h, prevOut.Value)
+	writeSigHashInt(h, vin.Sequence)
+	h.Write(outputs)
+	writeSigHashInt(h, tx.LockTime)
+
+	return h.Sum(nil)
+}
+
+func writeOutpoint(h hash.Hash, vin TXInput) {
+	writeSigHashBytes(h, vin.Txid)
+	writeSigHashInt(h, vin.Vout)
+}
+
+func writeOutput(h hash.Hash, vout TXOutput) {
+	writeSigHashInt(h, vout.Value)
+	writeSigHashBytes(h, vout.ScriptPubKey)
+}
+
+func writeSigHashInt(h hash.Hash, n int) {
+	buff := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buff, uint64(n))
+	h.Write(buff)
+}
+
+// Byte strings are prefixed with their length so fields can't run into
+// each other
+func writeSigHashBytes(h hash.Hash, data []byte) {
+	writeSigHashInt(h, len(data))
+	h.Write(data)
+}
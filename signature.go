@@ -0,0 +1,78 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+)
+
+var errNonCanonicalSignature = errors.New("Signature is not canonical")
+
+// SignHash signs a hash with the private key. The signature is r and s
+// padded to the size of the curve order, with s in its lower half
+func SignHash(privKey ecdsa.PrivateKey, hash []byte) []byte {
+	r, s, err := ecdsa.Sign(rand.Reader, &privKey, hash)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	// Both s and n - s are valid, only the low one is accepted
+	n := privKey.Curve.Params().N
+	if s.Cmp(halfOrder(n)) > 0 {
+		s.Sub(n, s)
+	}
+
+	return encodeSignature(privKey.Curve, r, s)
+}
+
+// VerifyHash checks a signature of a hash against a public key of any
+// key type
+func VerifyHash(pubKey, hash, signature []byte) bool {
+	return anyKeyVerifier().VerifyHash(pubKey, hash, signature)
+}
+
+// Checks a signature made by SignHash
+func verifyECDSA(pubKey *ecdsa.PublicKey, hash, signature []byte) bool {
+	r, s, err := parseSignature(pubKey.Curve, signature)
+	if err != nil {
+		return false
+	}
+
+	return ecdsa.Verify(pubKey, hash, r, s)
+}
+
+// Encodes r and s, each padded to the size of the curve order
+func encodeSignature(curve elliptic.Curve, r, s *big.Int) []byte {
+	size := (curve.Params().N.BitLen() + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+
+	return signature
+}
+
+// Parses a signature made by encodeSignature, rejecting any other
+// encoding of the same signature so it can't be altered
+func parseSignature(curve elliptic.Curve, signature []byte) (*big.Int, *big.Int, error) {
+	n := curve.Params().N
+	size := (n.BitLen() + 7) / 8
+	if len(signature) != 2*size {
+		return nil, nil, errNonCanonicalSignature
+	}
+
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || s.Sign() == 0 || s.Cmp(halfOrder(n)) > 0 {
+		return nil, nil, errNonCanonicalSignature
+	}
+
+	return r, s, nil
+}
+
+func halfOrder(n *big.Int) *big.Int {
+	return new(big.Int).Rsh(n, 1)
+}
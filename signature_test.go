@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignatureEncoding(t *testing.T) {
+	privKey, pubKey := newKeyPair(elliptic.P256())
+	n := privKey.Curve.Params().N
+
+	// Enough signatures to hit r and s with leading zero bytes
+	for i := 0; i < 300; i++ {
+		hash := sha256.Sum256([]byte{byte(i), byte(i >> 8)})
+		signature := SignHash(privKey, hash[:])
+
+		assert.Equal(t, 64, len(signature))
+		assert.True(t, VerifyHash(pubKey, hash[:], signature), "Signature %d verifies", i)
+
+		s := new(big.Int).SetBytes(signature[32:])
+		assert.True(t, s.Cmp(halfOrder(n)) <= 0, "s is in the lower half")
+	}
+}
+
+func TestSignatureStrictParsing(t *testing.T) {
+	privKey, pubKey := newKeyPair(elliptic.P256())
+	hash := sha256.Sum256([]byte("message"))
+	signature := SignHash(privKey, hash[:])
+	n := privKey.Curve.Params().N
+
+	// n - s is a valid ECDSA signature too, but not the canonical one
+	s := new(big.Int).SetBytes(signature[32:])
+	highS := encodeSignature(privKey.Curve, new(big.Int).SetBytes(signature[:32]), new(big.Int).Sub(n, s))
+	assert.False(t, VerifyHash(pubKey, hash[:], highS), "High S is rejected")
+
+	padded := append([]byte{0}, signature...)
+	assert.False(t, VerifyHash(pubKey, hash[:], padded), "Extra bytes are rejected")
+	assert.False(t, VerifyHash(pubKey, hash[:], signature[1:]), "Missing bytes are rejected")
+
+	zeroR := append(make([]byte, 32), signature[32:]...)
+	assert.False(t, VerifyHash(pubKey, hash[:], zeroR), "Zero r is rejected")
+}
@@ -2,26 +2,34 @@ package main
 
 import (
 	"bytes"
-	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/gob"
 	"encoding/hex"
 	"fmt"
 	"log"
-	"math/big"
+	"math"
+	"strings"
 )
 
 // Amount of reward. Mining the genesis block produed 50 BTC,
 // and every 210000 blocks the reward is halved.
 const subsidy = 10
 
+// Lock times below this are block heights, the others unix timestamps
+const lockTimeThreshold = 500000000
+
 // Transaction represents a Bitcoin transaction
 type Transaction struct {
 	ID   []byte
 	Vin  []TXInput
 	Vout []TXOutput
+	// Block height, or unix time when not below lockTimeThreshold, from
+	// which the transaction can be included in a block. 0 for none
+	LockTime int
+	// Unlocking scripts of the inputs in the same order. They are left out
+	// of the ID, so changing them can't change it
+	Witness []Script
 }
 
 // IsCoinbase checks whether the transaction is coinbase
@@ -29,122 +37,308 @@ func (tx Transaction) IsCoinbase() bool {
 	return len(tx.Vin) == 1 && len(tx.Vin[0].Txid) == 0 && tx.Vin[0].Vout == -1
 }
 
+// HasValidOutputValues checks that no output value is negative and that
+// their sum doesn't overflow
+func (tx Transaction) HasValidOutputValues() bool {
+	total := 0
+
+	for _, out := range tx.Vout {
+		if out.Value < 0 || out.Value > math.MaxInt64-total {
+			return false
+		}
+		total += out.Value
+	}
+
+	return true
+}
+
+// Fee returns how much the outputs spent by the transaction, found in
+// prevTXs, are worth beyond its own outputs
+func (tx Transaction) Fee(prevTXs map[string]Transaction) int {
+	fee := 0
+
+	for _, vin := range tx.Vin {
+		fee += prevTXs[hex.EncodeToString(vin.Txid)].Vout[vin.Vout].Value
+	}
+	for _, out := range tx.Vout {
+		fee -= out.Value
+	}
+
+	return fee
+}
+
+// IsFinal checks whether the lock time of the transaction has elapsed for
+// a block at the given height and time
+func (tx Transaction) IsFinal(height int, blockTime int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+
+	if tx.LockTime < lockTimeThreshold {
+		return height >= tx.LockTime
+	}
+
+	return blockTime >= int64(tx.LockTime)
+}
+
 func NewCoinbaseTX(to, data string) *Transaction {
 	if data == "" {
-		data = fmt.Sprintf("Regard to '%s'", to)
+		// Random data keeps coinbase transactions to the same address unique
+		randData := make([]byte, 20)
+		_, err := rand.Read(randData)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		data = fmt.Sprintf("%x", randData)
 	}
 
-	txin := TXInput{[]byte{}, -1, nil, []byte(data)}
+	txin := TXInput{[]byte{}, -1, NewScriptBuilder().AddData([]byte(data)).Script(), 0}
 	txout := NewTXOutput(subsidy, to)
-	tx := Transaction{nil, []TXInput{txin}, []TXOutput{*txout}}
+	tx := Transaction{nil, []TXInput{txin}, []TXOutput{*txout}, 0, nil}
 	tx.ID = tx.Hash()
 
 	return &tx
 }
 
-// NewUTXOTransaction creates a new Transaction
-func NewUTXOTransaction(from, to string, amount int, UTXOSet *UTXOSet) *Transaction {
-	inputs := []TXInput{}
-	outputs := []TXOutput{}
+// Recipient is an address paid by a transaction
+type Recipient struct {
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+}
+
+// NewUTXOTransaction creates a new Transaction funded with the outputs picked
+// by selector. Every input is charged feePerInput, which is left to the miner
+func NewUTXOTransaction(from, to string, amount int, selector CoinSelector, feePerInput int, UTXOSet *UTXOSet) *Transaction {
+	return NewSendManyTransaction(from, []Recipient{{to, amount}}, selector, feePerInput, UTXOSet)
+}
 
+// NewSendManyTransaction creates a Transaction with an output for every
+// recipient and the change going back to from
+func NewSendManyTransaction(from string, recipients []Recipient, selector CoinSelector, feePerInput int, UTXOSet *UTXOSet) *Transaction {
 	wallets, err := NewWallets()
 	if err != nil {
 		log.Panic(err)
 	}
 
-	wallet := wallets.GetWallet(from)
-	pubKeyHash := HashPubKey(wallet.PublicKey)
-	acc, validOutputs := UTXOSet.FindSpendableOutputs(pubKeyHash, amount)
-
-	if acc < amount {
-		log.Panic("Not enough funds")
-	}
+	return NewWalletTransaction(wallets, []string{from}, from, recipients, selector, feePerInput, UTXOSet)
+}
 
-	// validOutputs is a map
-	// Build a list of inputs
-	for txid, outputsIndices := range validOutputs {
-		txID, err := hex.DecodeString(txid)
+// NewWalletTransaction creates a Transaction paying the recipients with
+// outputs of any of the from addresses, as given by
+// Wallets.FundingAddresses. Each input is signed with the key of its own
+// address and the change goes to the change address
+func NewWalletTransaction(wallets *Wallets, from []string, change string, recipients []Recipient, selector CoinSelector, feePerInput int, UTXOSet *UTXOSet) *Transaction {
+	UTXOs := []UTXO{}
+	for _, address := range from {
+		wallet, err := wallets.SpendingWallet(address)
 		if err != nil {
 			log.Panic(err)
 		}
 
-		for _, outputIndex := range outputsIndices {
-			input := TXInput{txID, outputIndex, nil, wallet.PublicKey}
-			inputs = append(inputs, input)
-		}
+		UTXOs = append(UTXOs, UTXOSet.FindSpendableUTXOs(HashPubKey(wallet.PublicKey))...)
+	}
+
+	tx, err := NewFundedTransaction(UTXOs, recipients, change, selector, feePerInput)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	UTXOSet.Blockchain.SignTransactionWithWallets(tx, wallets)
+	tx.ID = tx.Hash()
+
+	return tx
+}
+
+// NewFundedTransaction creates an unsigned Transaction paying the
+// recipients with outputs picked from UTXOs by selector
+func NewFundedTransaction(UTXOs []UTXO, recipients []Recipient, change string, selector CoinSelector, feePerInput int) (*Transaction, error) {
+	inputs := []TXInput{}
+	outputs := []TXOutput{}
+
+	amount := 0
+	for _, recipient := range recipients {
+		amount += recipient.Amount
+	}
+
+	selected, err := selector.Select(UTXOs, amount, feePerInput)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build a list of inputs
+	acc := 0
+	for _, utxo := range selected {
+		inputs = append(inputs, TXInput{utxo.Txid, utxo.Vout, nil, 0})
+		acc += utxo.Output.Value
 	}
 
 	// Build a list of outputs
-	outputs = append(outputs, *NewTXOutput(amount, to))
-	if acc >= amount {
-		outputs = append(outputs, *NewTXOutput(acc-amount, from))
+	for _, recipient := range recipients {
+		outputs = append(outputs, *NewTXOutput(recipient.Amount, recipient.Address))
+	}
+	changeAmount := acc - amount - feePerInput*len(inputs)
+	if changeAmount > 0 {
+		outputs = append(outputs, *NewTXOutput(changeAmount, change))
 	}
 
-	tx := Transaction{nil, inputs, outputs}
+	tx := Transaction{nil, inputs, outputs, 0, nil}
 	tx.ID = tx.Hash()
-	UTXOSet.Blockchain.SignTransaction(&tx, wallet.PrivateKey)
 
-	return &tx
+	return &tx, nil
 }
 
 // Sign each input of a transaction
-func (tx *Transaction) Sign(privKey ecdsa.PrivateKey, prevTXs map[string]Transaction) {
+func (tx *Transaction) Sign(signer Signer, prevTXs map[string]Transaction, hashType SigHashType) {
 	if tx.IsCoinbase() {
 		return
 	}
 
-	txTrimmed := tx.TrimmedCopy()
-
-	for index, vin := range txTrimmed.Vin {
-		prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
-		txTrimmed.Vin[index].Signature = nil
-
-		// Pubkey is set to the PubKeyHash of the referenced output
-		txTrimmed.Vin[index].PubKey = prevTx.Vout[vin.Vout].PubKeyHash
-
-		// This hash is the data to be signed
-		txTrimmed.ID = txTrimmed.Hash()
+	hashes := NewSigHashes(tx)
+	for index := range tx.Vin {
+		tx.SignInput(hashes, index, signer, prevTXs, hashType)
+	}
+}
 
-		// Reset
-		txTrimmed.Vin[index].PubKey = nil
+// SignInput signs a single P2PKH input of a transaction, so inputs locked
+// with different keys can be signed one by one
+func (tx *Transaction) SignInput(hashes *SigHashes, index int, signer Signer, prevTXs map[string]Transaction, hashType SigHashType) {
+	vin := tx.Vin[index]
+	prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
+	signature := tx.InputSignature(hashes, index, signer, prevTx.Vout[vin.Vout], hashType)
 
-		// Sign ID with private key
-		r, s, err := ecdsa.Sign(rand.Reader, &privKey, txTrimmed.ID)
-		if err != nil {
-			log.Panic(err)
-		}
+	tx.SetWitness(index, NewP2PKHScriptSig(signature, signer.PubKey()))
+}
 
-		tx.Vin[index].Signature = append(r.Bytes(), s.Bytes()...)
+// InputSignature signs the input spending prevOut and appends hashType
+// to the signature
+func (tx *Transaction) InputSignature(hashes *SigHashes, index int, signer Signer, prevOut TXOutput, hashType SigHashType) []byte {
+	hash := tx.SignatureHash(hashes, index, prevOut, hashType)
+	if hash == nil {
+		log.Panicf("ERROR: Input %d can't be signed with %s", index, hashType)
 	}
+
+	return append(signer.Sign(hash), byte(hashType))
 }
 
-// TrimmedCopy creates a trimmed copy of Transaction to be used in signing
+// TrimmedCopy creates a copy of Transaction without the witness
 func (tx *Transaction) TrimmedCopy() Transaction {
 	inputs := []TXInput{}
 	outputs := []TXOutput{}
 
 	for _, vin := range tx.Vin {
-		// trimmed away signature and pubkey
-		inputs = append(inputs, TXInput{vin.Txid, vin.Vout, nil, nil})
+		inputs = append(inputs, TXInput{vin.Txid, vin.Vout, vin.ScriptSig, vin.Sequence})
 	}
 
 	for _, vout := range tx.Vout {
-		outputs = append(outputs, TXOutput{vout.Value, vout.PubKeyHash})
+		outputs = append(outputs, TXOutput{vout.Value, vout.ScriptPubKey})
 	}
 
-	return Transaction{tx.ID, inputs, outputs}
+	return Transaction{tx.ID, inputs, outputs, tx.LockTime, nil}
 }
 
-// Hash returns the hash of the Transaction
+// SetWitness sets the unlocking script of an input
+func (tx *Transaction) SetWitness(index int, script Script) {
+	for len(tx.Witness) < len(tx.Vin) {
+		tx.Witness = append(tx.Witness, nil)
+	}
+
+	tx.Witness[index] = script
+}
+
+// InputWitness returns the unlocking script of an input, or nil
+func (tx Transaction) InputWitness(index int) Script {
+	if index >= len(tx.Witness) {
+		return nil
+	}
+
+	return tx.Witness[index]
+}
+
+// InputUsesKey checks whether the public key hash unlocked an input
+func (tx Transaction) InputUsesKey(index int, pubKeyHash []byte) bool {
+	pushes := tx.InputWitness(index).PushedData()
+	if len(pushes) != 2 {
+		return false
+	}
+
+	// A P2PKH unlocking script pushes a signature and a public key
+	lockingHash := HashPubKey(pushes[1])
+
+	return bytes.Compare(lockingHash, pubKeyHash) == 0
+}
+
+// HasValidWitness checks that the unlocking scripts are all in the
+// witness, leaving the ID nothing to be tampered with
+func (tx Transaction) HasValidWitness() bool {
+	if len(tx.Witness) > len(tx.Vin) {
+		return false
+	}
+
+	for _, vin := range tx.Vin {
+		if len(vin.ScriptSig) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Hash returns the ID of the Transaction, which covers everything but
+// the witness
 func (tx *Transaction) Hash() []byte {
 	hash := [32]byte{}
 	txCopy := *tx
 	txCopy.ID = []byte{}
+	txCopy.Witness = nil
+
+	hash = sha256.Sum256(txCopy.Serialize())
+	return hash[:]
+}
+
+// WitnessHash returns the hash of the whole Transaction, witness included
+func (tx *Transaction) WitnessHash() []byte {
+	hash := [32]byte{}
+	txCopy := *tx
+	txCopy.ID = []byte{}
 
 	hash = sha256.Sum256(txCopy.Serialize())
 	return hash[:]
 }
 
+// String returns a human-readable representation of a transaction
+func (tx Transaction) String() string {
+	lines := []string{}
+
+	lines = append(lines, fmt.Sprintf("--- Transaction %x:", tx.ID))
+	if tx.LockTime != 0 {
+		lines = append(lines, fmt.Sprintf("     Lock time: %d", tx.LockTime))
+	}
+
+	for i, input := range tx.Vin {
+		lines = append(lines, fmt.Sprintf("     Input %d:", i))
+		lines = append(lines, fmt.Sprintf("       TXID:      %x", input.Txid))
+		lines = append(lines, fmt.Sprintf("       Out:       %d", input.Vout))
+		if len(input.ScriptSig) != 0 {
+			lines = append(lines, fmt.Sprintf("       ScriptSig: %s", input.ScriptSig))
+		}
+		if witness := tx.InputWitness(i); witness != nil {
+			lines = append(lines, fmt.Sprintf("       Witness:   %s", witness))
+		}
+		if input.Sequence != 0 {
+			lines = append(lines, fmt.Sprintf("       Sequence:  %d", input.Sequence))
+		}
+	}
+
+	for i, output := range tx.Vout {
+		lines = append(lines, fmt.Sprintf("     Output %d:", i))
+		lines = append(lines, fmt.Sprintf("       Value:        %d", output.Value))
+		lines = append(lines, fmt.Sprintf("       ScriptPubKey: %s", output.ScriptPubKey))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 // Serialize transaction
 func (tx *Transaction) Serialize() []byte {
 	encoded := bytes.Buffer{}
@@ -169,43 +363,69 @@ func (tx *Transaction) Verify(prevTXs map[string]Transaction) bool {
 		}
 	}
 
-	txTrimmed := tx.TrimmedCopy()
+	for _, check := range newInputChecks(tx, prevTXs, anyKeyVerifier()) {
+		if check.verify() != nil {
+			return false
+		}
+	}
 
-	// Curve that generates key pairs
-	curve := elliptic.P256()
+	return true
+}
 
-	for index, vin := range tx.Vin {
-		prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
-		txTrimmed.Vin[index].Signature = nil
+// txSignatureChecker checks signatures of an input against the transaction
+type txSignatureChecker struct {
+	tx       *Transaction
+	index    int
+	prevOut  TXOutput
+	hashes   *SigHashes
+	verifier Verifier
+}
 
-		// Pubkey is set to the PubKeyHash of the referenced output
-		txTrimmed.Vin[index].PubKey = prevTx.Vout[vin.Vout].PubKeyHash
+// CheckSig implements SignatureChecker. The last byte of the signature
+// is its SigHashType
+func (c txSignatureChecker) CheckSig(signature, pubKey []byte) bool {
+	if len(signature) < 2 {
+		return false
+	}
 
-		// This hash is the data to be signed
-		txTrimmed.ID = txTrimmed.Hash()
+	hashType := SigHashType(signature[len(signature)-1])
+	if !hashType.IsValid() {
+		return false
+	}
 
-		// Reset
-		txTrimmed.Vin[index].PubKey = nil
+	hash := c.tx.SignatureHash(c.hashes, c.index, c.prevOut, hashType)
+	if hash == nil {
+		return false
+	}
 
-		// Signature is a pair of number
-		r := big.Int{}
-		s := big.Int{}
-		sigLen := len(vin.Signature)
-		r.SetBytes(vin.Signature[:(sigLen / 2)])
-		s.SetBytes(vin.Signature[(sigLen / 2):])
+	return c.verifier.VerifyHash(pubKey, hash, signature[:len(signature)-1])
+}
 
-		// Public key is a pair of coordinates
-		x := big.Int{}
-		y := big.Int{}
-		keyLen := len(vin.PubKey)
-		x.SetBytes(vin.PubKey[:(keyLen / 2)])
-		y.SetBytes(vin.PubKey[(keyLen / 2):])
+// CheckLockTime implements SignatureChecker. The lock time of the
+// transaction has to be of the same kind and at least lockTime
+func (c txSignatureChecker) CheckLockTime(lockTime int) bool {
+	if (lockTime < lockTimeThreshold) != (c.tx.LockTime < lockTimeThreshold) {
+		return false
+	}
 
-		rawPubKey := ecdsa.PublicKey{curve, &x, &y}
-		if ecdsa.Verify(&rawPubKey, txTrimmed.ID, &r, &s) == false {
-			return false
-		}
+	return c.tx.LockTime >= lockTime
+}
+
+// CheckSequence implements SignatureChecker. The input has to be
+// relatively locked for at least sequence blocks
+func (c txSignatureChecker) CheckSequence(sequence int) bool {
+	return c.tx.Vin[c.index].Sequence >= sequence
+}
+
+// DeserializeTransaction deserializes a transaction
+func DeserializeTransaction(data []byte) Transaction {
+	transaction := Transaction{}
+
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(&transaction)
+	if err != nil {
+		log.Panic(err)
 	}
 
-	return true
+	return transaction
 }
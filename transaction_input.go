@@ -1,18 +1,12 @@
 package main
 
-import "bytes"
-
 // TXInput represents a transaction input
 type TXInput struct {
-	Txid      []byte
-	Vout      int // Index of an output in the transaction
-	Signature []byte
-	PubKey    []byte
-}
-
-// Checks whether the public keyhash initiated the transaction
-func (in *TXInput) UsesKey(pubKeyHash []byte) bool {
-	lockingHash := HashPubKey(in.PubKey)
-
-	return bytes.Compare(lockingHash, pubKeyHash) == 0
+	Txid []byte
+	Vout int // Index of an output in the transaction
+	// Part of the ID, only used for the data of coinbase transactions.
+	// Other inputs are unlocked by the transaction witness
+	ScriptSig Script
+	// Relative lock time in blocks since the spent output was mined, 0 for none
+	Sequence int
 }
@@ -8,19 +8,29 @@ import (
 
 // TXOutput represents a transaction output
 type TXOutput struct {
-	Value      int
-	PubKeyHash []byte
+	Value        int
+	ScriptPubKey Script
 }
 
 // Signs the output
 func (out *TXOutput) Lock(address []byte) {
-	pubKeyHash := Base58Decode(address)
-	out.PubKeyHash = pubKeyHash[1 : len(pubKeyHash)-4]
+	out.ScriptPubKey = AddressScript(string(address))
 }
 
-// Checks if the output can be used by the owner of the pubkey
+// Checks if the output can be used by the owner of the pubkey, or of the
+// redeem script, hashing to pubKeyHash
 func (out *TXOutput) IsLockedWithKey(pubKeyHash []byte) bool {
-	return bytes.Compare(out.PubKeyHash, pubKeyHash) == 0
+	if scriptHash := out.ScriptPubKey.ScriptHash(); scriptHash != nil {
+		return bytes.Compare(scriptHash, pubKeyHash) == 0
+	}
+
+	return bytes.Compare(out.ScriptPubKey.PubKeyHash(), pubKeyHash) == 0
+}
+
+// IsUnspendable checks if the output can never be spent, so it is kept
+// out of the UTXO set
+func (out *TXOutput) IsUnspendable() bool {
+	return out.ScriptPubKey.IsUnspendable()
 }
 
 // Create a new TXOutput
@@ -31,9 +41,22 @@ func NewTXOutput(value int, address string) *TXOutput {
 	return &txo
 }
 
-// TXOutputs collects TXOutput
+// TXOutputs collects the unspent outputs of a transaction, keyed by their
+// index in Vout, together with where the transaction was mined
 type TXOutputs struct {
-	Outputs []TXOutput
+	Outputs  map[int]TXOutput
+	Coinbase bool
+	Height   int
+}
+
+// NewTXOutputs creates an empty TXOutputs for a transaction mined at height
+func NewTXOutputs(tx *Transaction, height int) TXOutputs {
+	return TXOutputs{make(map[int]TXOutput), tx.IsCoinbase(), height}
+}
+
+// IsMature checks if the outputs can be spent in a block at the given height
+func (outs TXOutputs) IsMature(height, maturity int) bool {
+	return !outs.Coinbase || height-outs.Height >= maturity
 }
 
 // Serialize serializes TXOutputs
@@ -54,7 +77,7 @@ func DeserializeOutputs(data []byte) TXOutputs {
 	outputs := TXOutputs{}
 
 	dec := gob.NewDecoder(bytes.NewReader(data))
-	err := dec.Decode(outputs)
+	err := dec.Decode(&outputs)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionIsFinal(t *testing.T) {
+	tx := Transaction{LockTime: 100}
+	assert.False(t, tx.IsFinal(99, 0))
+	assert.True(t, tx.IsFinal(100, 0))
+
+	tx.LockTime = lockTimeThreshold + 100
+	assert.False(t, tx.IsFinal(1000000, lockTimeThreshold+99), "Timestamp lock ignores height")
+	assert.True(t, tx.IsFinal(0, lockTimeThreshold+100))
+
+	assert.True(t, Transaction{}.IsFinal(0, 0), "No lock time is always final")
+}
+
+func TestSigHashTypes(t *testing.T) {
+	privKey, pubKey := newKeyPair(elliptic.P256())
+	prevOut := TXOutput{10, NewP2PKHScript(HashPubKey(pubKey))}
+	newTx := func() Transaction {
+		inputs := []TXInput{{[]byte{1}, 0, nil, 0}, {[]byte{2}, 0, nil, 0}}
+		outputs := []TXOutput{{5, nil}, {4, nil}}
+		return Transaction{nil, inputs, outputs, 0, nil}
+	}
+	checks := func(tx Transaction, signature []byte) bool {
+		return txSignatureChecker{&tx, 0, prevOut, NewSigHashes(&tx), anyKeyVerifier()}.CheckSig(signature, pubKey)
+	}
+
+	tx := newTx()
+	signature := tx.InputSignature(NewSigHashes(&tx), 0, NewSigner(privKey), prevOut, SigHashAll)
+	assert.True(t, checks(tx, signature))
+	tx.Vout[1].Value = 3
+	assert.False(t, checks(tx, signature), "ALL commits to every output")
+
+	tx = newTx()
+	signature = tx.InputSignature(NewSigHashes(&tx), 0, NewSigner(privKey), prevOut, SigHashAll)
+	spent := TXOutput{9, prevOut.ScriptPubKey}
+	assert.False(t, txSignatureChecker{&tx, 0, spent, NewSigHashes(&tx), anyKeyVerifier()}.CheckSig(signature, pubKey), "Signatures commit to the spent value")
+
+	tx = newTx()
+	signature = tx.InputSignature(NewSigHashes(&tx), 0, NewSigner(privKey), prevOut, SigHashAll|SigHashAnyoneCanPay)
+	tx.Vin = append(tx.Vin, TXInput{[]byte{3}, 0, nil, 0})
+	assert.True(t, checks(tx, signature), "ANYONECANPAY lets inputs be added")
+
+	tx = newTx()
+	signature = tx.InputSignature(NewSigHashes(&tx), 0, NewSigner(privKey), prevOut, SigHashSingle)
+	tx.Vout[1].Value = 3
+	assert.True(t, checks(tx, signature), "SINGLE only commits to its own output")
+	tx.Vout[0].Value = 3
+	assert.False(t, checks(tx, signature))
+
+	tx = newTx()
+	signature = tx.InputSignature(NewSigHashes(&tx), 0, NewSigner(privKey), prevOut, SigHashNone)
+	tx.Vout = tx.Vout[:1]
+	assert.True(t, checks(tx, signature), "NONE lets outputs change")
+
+	signature[len(signature)-1] = 0x04
+	assert.False(t, checks(tx, signature), "Unknown hash types are rejected")
+}
+
+func TestParseSigHashType(t *testing.T) {
+	hashType, err := ParseSigHashType("single|anyonecanpay")
+	assert.Nil(t, err)
+	assert.Equal(t, SigHashSingle|SigHashAnyoneCanPay, hashType)
+	assert.Equal(t, "SINGLE|ANYONECANPAY", hashType.String())
+
+	_, err = ParseSigHashType("ANYONECANPAY")
+	assert.NotNil(t, err)
+}
+
+// Creates a transaction spending inputs outputs locked to a single key,
+// signed, together with the transactions it spends
+func newBenchmarkTransaction(inputs int) (*Transaction, map[string]Transaction) {
+	privKey, pubKey := newKeyPair(elliptic.P256())
+	prevTXs := make(map[string]Transaction)
+
+	tx := Transaction{nil, []TXInput{}, []TXOutput{{inputs, NewP2PKHScript(HashPubKey(pubKey))}}, 0, nil}
+	for i := 0; i < inputs; i++ {
+		prevTx := Transaction{nil, []TXInput{}, []TXOutput{{1, NewP2PKHScript(HashPubKey(pubKey))}}, i, nil}
+		prevTx.ID = prevTx.Hash()
+		prevTXs[hex.EncodeToString(prevTx.ID)] = prevTx
+
+		tx.Vin = append(tx.Vin, TXInput{prevTx.ID, 0, nil, 0})
+	}
+
+	tx.Sign(NewSigner(privKey), prevTXs, SigHashAll)
+	tx.ID = tx.Hash()
+
+	return &tx, prevTXs
+}
+
+func BenchmarkVerify(b *testing.B) {
+	for _, inputs := range []int{10, 100, 500} {
+		tx, prevTXs := newBenchmarkTransaction(inputs)
+
+		b.Run(fmt.Sprintf("%d inputs", inputs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if !tx.Verify(prevTXs) {
+					b.Fatal("Transaction doesn't verify")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSignatureHash(b *testing.B) {
+	for _, inputs := range []int{10, 100, 500} {
+		tx, prevTXs := newBenchmarkTransaction(inputs)
+		prevOut := prevTXs[hex.EncodeToString(tx.Vin[0].Txid)].Vout[0]
+
+		b.Run(fmt.Sprintf("%d inputs", inputs), func(b *testing.B) {
+			hashes := NewSigHashes(tx)
+			for i := 0; i < b.N; i++ {
+				tx.SignatureHash(hashes, i%inputs, prevOut, SigHashAll)
+			}
+		})
+	}
+}
+
+func TestTransactionIDExcludesWitness(t *testing.T) {
+	tx, prevTXs := newBenchmarkTransaction(2)
+	id := tx.Hash()
+	witnessHash := tx.WitnessHash()
+
+	// ECDSA signatures are randomized, so signing again changes the witness
+	privKey, _ := newKeyPair(elliptic.P256())
+	tx.SignInput(NewSigHashes(tx), 0, NewSigner(privKey), prevTXs, SigHashAll)
+
+	assert.Equal(t, id, tx.Hash(), "ID doesn't depend on the witness")
+	assert.NotEqual(t, witnessHash, tx.WitnessHash())
+	assert.True(t, tx.HasValidWitness())
+
+	tx.Vin[1].ScriptSig = Script{OP_1}
+	assert.False(t, tx.HasValidWitness(), "Unlocking data outside of the witness is rejected")
+}
+
+func TestNewSendManyTransaction(t *testing.T) {
+	wallet := NewWallet(KeyTypeP256)
+	bc := newTestBlockchain(t, wallet)
+	walletsWithKey(t, wallet).SaveToFile()
+
+	from := string(wallet.GetAddress())
+	recipients := []Recipient{
+		{string(NewWallet(KeyTypeP256).GetAddress()), 3},
+		{string(NewWallet(KeyTypeP256).GetAddress()), 4},
+	}
+	tx := NewSendManyTransaction(from, recipients, LargestFirstSelector{}, 2, &UTXOSet{bc})
+
+	// One output per recipient, then the change left after the fee
+	assert.Equal(t, 1, len(tx.Vin))
+	assert.Equal(t, len(recipients)+1, len(tx.Vout))
+	for index, recipient := range recipients {
+		assert.Equal(t, *NewTXOutput(recipient.Amount, recipient.Address), tx.Vout[index])
+	}
+	assert.Equal(t, *NewTXOutput(subsidy-3-4-2, from), tx.Vout[2])
+	assert.Equal(t, 2, bc.TransactionFee(tx))
+	assert.Nil(t, bc.VerifyTransactions([]*Transaction{tx}))
+}
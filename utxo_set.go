@@ -14,27 +14,74 @@ type UTXOSet struct {
 	Blockchain *Blockchain
 }
 
-// FindSpendableOutputs finds and returns unspent outputs to reference in inputs
-func (u UTXOSet) FindSpendableOutputs(pubkeyHash []byte, amount int) (int, map[string][]int) {
-	unspentOutputs := make(map[string][]int)
-	accumulated := 0
+// FindSpendableUTXOs returns every unspent output locked with the key that
+// can be spent in the next block
+func (u UTXOSet) FindSpendableUTXOs(pubKeyHash []byte) []UTXO {
+	UTXOs := []UTXO{}
+	height := u.Blockchain.GetBestHeight() + 1
+	maturity := u.Blockchain.config.CoinbaseMaturity
 	db := u.Blockchain.db
 
 	err := db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(utxoBucket))
-		cursor := b.Cursor()
+		c := b.Cursor()
 
-		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
-			txID := hex.EncodeToString(key)
+		for key, value := c.First(); key != nil; key, value = c.Next() {
 			outputs := DeserializeOutputs(value)
+			if !outputs.IsMature(height, maturity) {
+				continue
+			}
 
 			for outputIndex, output := range outputs.Outputs {
-				if output.IsLockedWithKey(pubkeyHash) && accumulated < amount {
-					accumulated += output.Value
-					unspentOutputs[txID] = append(unspentOutputs[txID], outputIndex)
+				if output.IsLockedWithKey(pubKeyHash) {
+					txID := append([]byte{}, key...)
+					UTXOs = append(UTXOs, UTXO{txID, outputIndex, output})
 				}
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return UTXOs
+}
+
+// IsSpendable checks that every input of the transaction references a
+// different unspent output that can be spent in a block at the given
+// height, once coinbase maturity and the relative lock time of the input
+// have elapsed
+func (u UTXOSet) IsSpendable(transaction *Transaction, height int) bool {
+	spendable := true
+	maturity := u.Blockchain.config.CoinbaseMaturity
+	db := u.Blockchain.db
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(utxoBucket))
+		spent := make(map[string]bool)
+
+		for _, vin := range transaction.Vin {
+			// Listing an output twice would count its value twice
+			if spent[outpointKey(vin.Txid, vin.Vout)] {
+				spendable = false
+				return nil
+			}
+			spent[outpointKey(vin.Txid, vin.Vout)] = true
+
+			outputsBytes := b.Get(vin.Txid)
+			if outputsBytes == nil {
+				spendable = false
+				return nil
+			}
+
+			outputs := DeserializeOutputs(outputsBytes)
+			if _, ok := outputs.Outputs[vin.Vout]; !ok || !outputs.IsMature(height, maturity) ||
+				height-outputs.Height < vin.Sequence {
+				spendable = false
+				return nil
+			}
+		}
 
 		return nil
 	})
@@ -42,7 +89,7 @@ func (u UTXOSet) FindSpendableOutputs(pubkeyHash []byte, amount int) (int, map[s
 		log.Panic(err)
 	}
 
-	return accumulated, unspentOutputs
+	return spendable
 }
 
 // Reindex rebuilds the UTXO set
@@ -85,11 +132,26 @@ func (u UTXOSet) Reindex() {
 		}
 		return nil
 	})
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// AddressUTXO is an unspent output with the address it pays to, and
+// whether it can be spent in the next block
+type AddressUTXO struct {
+	UTXO
+	Address string
+	Mature  bool
 }
 
-// FindUTXO returns all unspent transaction outputs
-func (u UTXOSet) FindUTXO(pubKeyHash []byte) []TXOutput {
-	UTXOs := []TXOutput{}
+// FindAddressUTXOs returns the unspent outputs paying to any of the
+// addresses
+func (u UTXOSet) FindAddressUTXOs(addresses []string) []AddressUTXO {
+	scripts := addressScripts(addresses)
+	UTXOs := []AddressUTXO{}
+	height := u.Blockchain.GetBestHeight() + 1
+	maturity := u.Blockchain.config.CoinbaseMaturity
 	db := u.Blockchain.db
 
 	err := db.View(func(tx *bolt.Tx) error {
@@ -98,10 +160,12 @@ func (u UTXOSet) FindUTXO(pubKeyHash []byte) []TXOutput {
 
 		for key, value := c.First(); key != nil; key, value = c.Next() {
 			outputs := DeserializeOutputs(value)
+			mature := outputs.IsMature(height, maturity)
 
-			for _, output := range outputs.Outputs {
-				if output.IsLockedWithKey(pubKeyHash) {
-					UTXOs = append(UTXOs, output)
+			for outputIndex, output := range outputs.Outputs {
+				if address, ok := scripts[string(output.ScriptPubKey)]; ok {
+					txID := append([]byte{}, key...)
+					UTXOs = append(UTXOs, AddressUTXO{UTXO{txID, outputIndex, output}, address, mature})
 				}
 			}
 		}
@@ -110,9 +174,58 @@ func (u UTXOSet) FindUTXO(pubKeyHash []byte) []TXOutput {
 	if err != nil {
 		log.Panic(err)
 	}
+
 	return UTXOs
 }
 
+// Returns the addresses by the locking scripts paying to them
+func addressScripts(addresses []string) map[string]string {
+	scripts := make(map[string]string)
+	for _, address := range addresses {
+		scripts[string(AddressScript(address))] = address
+	}
+
+	return scripts
+}
+
+// GetBalance returns the spendable and the immature amounts locked with
+// the public key hash
+func (u UTXOSet) GetBalance(pubKeyHash []byte) (int, int) {
+	spendable := 0
+	immature := 0
+	height := u.Blockchain.GetBestHeight() + 1
+	maturity := u.Blockchain.config.CoinbaseMaturity
+	db := u.Blockchain.db
+
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(utxoBucket))
+		c := b.Cursor()
+
+		for key, value := c.First(); key != nil; key, value = c.Next() {
+			outputs := DeserializeOutputs(value)
+			mature := outputs.IsMature(height, maturity)
+
+			for _, output := range outputs.Outputs {
+				if !output.IsLockedWithKey(pubKeyHash) {
+					continue
+				}
+
+				if mature {
+					spendable += output.Value
+				} else {
+					immature += output.Value
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return spendable, immature
+}
+
 // Update updates the UTXO set with transactions from the Block
 // The Block is considered to be the tip of a blockchain
 func (u UTXOSet) Update(block *Block) {
@@ -124,24 +237,17 @@ func (u UTXOSet) Update(block *Block) {
 		for _, tx := range block.Transactions {
 			if tx.IsCoinbase() == false {
 				for _, vin := range tx.Vin {
-					updatedOutputs := TXOutputs{}
 					outputsBytes := b.Get(vin.Txid)
 					outputs := DeserializeOutputs(outputsBytes)
+					delete(outputs.Outputs, vin.Vout)
 
-					for outputIndex, output := range outputs.Outputs {
-						if outputIndex != vin.Vout {
-							// Unspent outputs
-							updatedOutputs.Outputs = append(updatedOutputs.Outputs, output)
-						}
-					}
-
-					if len(updatedOutputs.Outputs) == 0 {
+					if len(outputs.Outputs) == 0 {
 						err := b.Delete(vin.Txid)
 						if err != nil {
 							log.Panic(err)
 						}
 					} else {
-						err := b.Put(vin.Txid, updatedOutputs.Serialize())
+						err := b.Put(vin.Txid, outputs.Serialize())
 						if err != nil {
 							log.Panic(err)
 						}
@@ -150,9 +256,14 @@ func (u UTXOSet) Update(block *Block) {
 			}
 
 			// Outputs at the tip of the chain
-			newOutputs := TXOutputs{}
-			for _, output := range tx.Vout {
-				newOutputs.Outputs = append(newOutputs.Outputs, output)
+			newOutputs := NewTXOutputs(tx, block.Height)
+			for outputIndex, output := range tx.Vout {
+				if !output.IsUnspendable() {
+					newOutputs.Outputs[outputIndex] = output
+				}
+			}
+			if len(newOutputs.Outputs) == 0 {
+				continue
 			}
 
 			err := b.Put(tx.ID, newOutputs.Serialize())
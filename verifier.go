@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// Environment variable overriding the number of verification workers
+const verifyWorkersEnv = "VERIFY_WORKERS"
+
+// inputCheck is the script verification of a single transaction input
+type inputCheck struct {
+	tx       *Transaction
+	index    int
+	prevOut  TXOutput
+	hashes   *SigHashes
+	verifier Verifier
+}
+
+// Runs the unlocking script of the input against the spent output
+func (c inputCheck) verify() error {
+	checker := txSignatureChecker{c.tx, c.index, c.prevOut, c.hashes, c.verifier}
+
+	err := VerifyScript(c.tx.InputWitness(c.index), c.prevOut.ScriptPubKey, checker)
+	if err != nil {
+		return fmt.Errorf("Input %d of transaction %x is invalid: %s", c.index, c.tx.ID, err)
+	}
+
+	return nil
+}
+
+// Creates the checks of every input of tx, prevTXs holding the
+// transactions referenced by the inputs
+func newInputChecks(tx *Transaction, prevTXs map[string]Transaction, verifier Verifier) []inputCheck {
+	checks := []inputCheck{}
+	hashes := NewSigHashes(tx)
+
+	for index, vin := range tx.Vin {
+		prevTx := prevTXs[hex.EncodeToString(vin.Txid)]
+		checks = append(checks, inputCheck{tx, index, prevTx.Vout[vin.Vout], hashes, verifier})
+	}
+
+	return checks
+}
+
+// ScriptVerifier verifies input scripts on a pool of workers
+type ScriptVerifier struct {
+	Workers int
+}
+
+// NewScriptVerifier creates a ScriptVerifier with a worker per CPU,
+// unless VERIFY_WORKERS sets a valid number of workers
+func NewScriptVerifier() ScriptVerifier {
+	workers, err := envVerifyWorkers()
+	if err != nil || workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return ScriptVerifier{workers}
+}
+
+// Returns the number of workers set with VERIFY_WORKERS, or 0 when it
+// isn't set
+func envVerifyWorkers() (int, error) {
+	value := os.Getenv(verifyWorkersEnv)
+	if value == "" {
+		return 0, nil
+	}
+
+	workers, err := strconv.Atoi(value)
+	if err != nil || workers < 1 {
+		return 0, fmt.Errorf("%s must be a positive number, not '%s'", verifyWorkersEnv, value)
+	}
+
+	return workers, nil
+}
+
+// Verify runs the checks concurrently and returns the first failure.
+// Checks that haven't started yet are skipped once one has failed
+func (v ScriptVerifier) Verify(checks []inputCheck) error {
+	workers := v.Workers
+	if workers > len(checks) {
+		workers = len(checks)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan inputCheck)
+	stop := make(chan struct{})
+	var failure error
+	var once sync.Once
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for check := range jobs {
+				if err := check.verify(); err != nil {
+					once.Do(func() {
+						failure = err
+						close(stop)
+					})
+				}
+			}
+		}()
+	}
+
+Feed:
+	for _, check := range checks {
+		select {
+		case jobs <- check:
+		case <-stop:
+			break Feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return failure
+}
+
+// VerifyBatch is like Verify, but checks the Schnorr signatures of all the
+// inputs together in a batch. A failing batch doesn't tell which signature
+// is invalid, and scripts may rely on signatures failing, so the checks
+// are then run again one signature at a time
+func (v ScriptVerifier) VerifyBatch(checks []inputCheck) error {
+	batch := NewSchnorrBatch()
+	batched := []inputCheck{}
+
+	for _, check := range checks {
+		if verifier, ok := check.verifier.(signatureVerifier); ok {
+			verifier.batch = batch
+			check.verifier = verifier
+		}
+		batched = append(batched, check)
+	}
+
+	if v.Verify(batched) == nil && batch.Verify() {
+		return nil
+	}
+
+	return v.Verify(checks)
+}
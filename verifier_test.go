@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScriptVerifier(t *testing.T) {
+	tx, prevTXs := newBenchmarkTransaction(20)
+	checks := newInputChecks(tx, prevTXs, anyKeyVerifier())
+
+	for _, workers := range []int{1, 4} {
+		verifier := ScriptVerifier{workers}
+		assert.Nil(t, verifier.Verify(checks))
+		assert.Nil(t, verifier.Verify(nil), "Nothing to check is valid")
+	}
+
+	// Swap the unlocking scripts of two inputs
+	tx.Witness[3], tx.Witness[7] = tx.Witness[7], tx.Witness[3]
+	for _, workers := range []int{1, 4} {
+		assert.NotNil(t, ScriptVerifier{workers}.Verify(checks))
+	}
+}
+
+func TestNewScriptVerifierWorkers(t *testing.T) {
+	t.Setenv(verifyWorkersEnv, "3")
+	assert.Equal(t, 3, NewScriptVerifier().Workers)
+
+	// An invalid number is reported by the CLI, the verifier ignores it
+	for _, value := range []string{"0", "-2", "many"} {
+		t.Setenv(verifyWorkersEnv, value)
+		_, err := envVerifyWorkers()
+		assert.NotNil(t, err)
+		assert.Equal(t, runtime.NumCPU(), NewScriptVerifier().Workers)
+	}
+}
+
+func BenchmarkScriptVerifier(b *testing.B) {
+	tx, prevTXs := newBenchmarkTransaction(500)
+	checks := newInputChecks(tx, prevTXs, anyKeyVerifier())
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("%d workers", workers), func(b *testing.B) {
+			verifier := ScriptVerifier{workers}
+			for i := 0; i < b.N; i++ {
+				if verifier.Verify(checks) != nil {
+					b.Fatal("Transaction doesn't verify")
+				}
+			}
+		})
+	}
+}
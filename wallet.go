@@ -6,12 +6,16 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
+	"fmt"
 	"log"
+	"math/big"
 
 	"golang.org/x/crypto/ripemd160"
 )
 
 const version = byte(0x00)
+const scriptVersion = byte(0x05)
 const addressChecksumLen = 4
 
 // Wallet stores private and public keys
@@ -20,19 +24,128 @@ type Wallet struct {
 	PublicKey  []byte
 }
 
-// Creates and returns a Wallet
-func NewWallet() *Wallet {
-	private, public := newKeyPair()
+// Creates and returns a Wallet with a key of the given type
+func NewWallet(keyType KeyType) *Wallet {
+	private, public := newKeyPair(keyType.Curve())
 	wallet := Wallet{private, public}
 
 	return &wallet
 }
 
+// Set in the key type byte of an encoded Wallet whose public key has the
+// legacy encoding
+const legacyWalletFlag = 0x80
+
+// GobEncode stores only the key type and the private key scalar, the
+// curve can't be encoded
+func (w *Wallet) GobEncode() ([]byte, error) {
+	if w.PrivateKey.D == nil {
+		return nil, errors.New("Wallet has no private key to encode")
+	}
+
+	size := (w.PrivateKey.Curve.Params().N.BitLen() + 7) / 8
+	data := make([]byte, 1+size)
+	data[0] = byte(w.KeyType())
+	if w.IsLegacy() {
+		data[0] |= legacyWalletFlag
+	}
+	w.PrivateKey.D.FillBytes(data[1:])
+
+	return data, nil
+}
+
+// GobDecode rebuilds the keys from the private key scalar. Wallets saved
+// before key types existed hold a bare 32 byte P-256 scalar
+func (w *Wallet) GobDecode(data []byte) error {
+	keyType := KeyTypeP256
+	legacy := false
+	if len(data) != 32 && len(data) != 0 {
+		keyType = KeyType(data[0] &^ legacyWalletFlag)
+		legacy = data[0]&legacyWalletFlag != 0
+		data = data[1:]
+	}
+	if !keyType.IsValid() || len(data) == 0 || (legacy && keyType != KeyTypeP256) {
+		return fmt.Errorf("Invalid wallet key of type %s", keyType)
+	}
+
+	privKey := newPrivateKey(keyType.Curve(), data)
+	if legacy {
+		*w = *newLegacyWallet(privKey)
+	} else {
+		*w = *newWalletFromKey(privKey)
+	}
+
+	return nil
+}
+
+// KeyType returns the type of the wallet key
+func (w Wallet) KeyType() KeyType {
+	return curveKeyType(w.PrivateKey.Curve)
+}
+
+// IsLegacy checks if the public key of the wallet has the encoding of
+// wallets made before SEC1 encodings, which its address is derived from
+func (w Wallet) IsLegacy() bool {
+	return isLegacyPubKey(w.PublicKey)
+}
+
+// Signer returns the Signer of the wallet key, which needs the private
+// key of an unlocked wallet
+func (w Wallet) Signer() (Signer, error) {
+	return w.SignerOfType(SigTypeECDSA)
+}
+
+// SignerOfType returns the Signer of the wallet key making signatures of
+// sigType
+func (w Wallet) SignerOfType(sigType SigType) (Signer, error) {
+	if w.PrivateKey.D == nil {
+		return nil, errWalletLocked
+	}
+	if sigType == SigTypeSchnorr {
+		return NewSchnorrSigner(w.PrivateKey)
+	}
+
+	// The signature goes with the public key the address is derived from
+	return ecdsaSigner{w.PrivateKey, w.PublicKey}, nil
+}
+
+// WIF returns the private key in the Wallet Import Format, which needs
+// the private key of an unlocked wallet
+func (w Wallet) WIF() (string, error) {
+	if w.PrivateKey.D == nil {
+		return "", errWalletLocked
+	}
+
+	return EncodeWIF(w.PrivateKey), nil
+}
+
 // Returns Wallet address
 func (w Wallet) GetAddress() []byte {
 	pubKeyHash := HashPubKey(w.PublicKey)
 
-	versionedPayload := append([]byte{version}, pubKeyHash...)
+	return encodeAddress(version, pubKeyHash)
+}
+
+// Returns the address of outputs locked to a redeem script
+func ScriptAddress(redeemScript Script) string {
+	return string(encodeAddress(scriptVersion, HashPubKey(redeemScript)))
+}
+
+// Returns the locking script paying to an address
+func AddressScript(address string) Script {
+	fullPayload := Base58Decode([]byte(address))
+	hash := fullPayload[1 : len(fullPayload)-addressChecksumLen]
+
+	if fullPayload[0] == scriptVersion {
+		return NewP2SHScript(hash)
+	}
+
+	return NewP2PKHScript(hash)
+}
+
+// Encodes a versioned hash with a checksum into an address
+func encodeAddress(version byte, hash []byte) []byte {
+	versionedPayload := append([]byte{version}, hash...)
 	checksum := checksum(versionedPayload)
 
 	fullPayload := append(versionedPayload, checksum...)
@@ -51,22 +164,58 @@ func HashPubKey(pubKey []byte) []byte {
 	return RIPEMD160Hasher.Sum(nil)
 }
 
-func newKeyPair() (ecdsa.PrivateKey, []byte) {
-	curve := elliptic.P256()
+func newKeyPair(curve elliptic.Curve) (ecdsa.PrivateKey, []byte) {
 	private, err := ecdsa.GenerateKey(curve, rand.Reader)
 	if err != nil {
 		log.Panic(err)
 	}
 
-	// Slice syntax
-	pubKey := append(private.PublicKey.X.Bytes(), private.PublicKey.Y.Bytes()...)
+	return *private, encodePubKey(private.PublicKey)
+}
+
+// Rebuilds a private key from its scalar
+func newPrivateKey(curve elliptic.Curve, d []byte) ecdsa.PrivateKey {
+	private := ecdsa.PrivateKey{}
+	private.Curve = curve
+	private.D = new(big.Int).SetBytes(d)
+	private.X, private.Y = curve.ScalarBaseMult(d)
 
-	return *private, pubKey
+	return private
+}
+
+// Creates a Wallet from an existing private key
+func newWalletFromKey(private ecdsa.PrivateKey) *Wallet {
+	return &Wallet{private, encodePubKey(private.PublicKey)}
+}
+
+// Creates a Wallet with the legacy encoding of the public key of an
+// existing P-256 private key, for the address it had before SEC1 encodings
+func newLegacyWallet(private ecdsa.PrivateKey) *Wallet {
+	return &Wallet{private, encodeLegacyPubKey(private.PublicKey)}
+}
+
+// Creates a Wallet knowing only the public key, which can't sign. A legacy
+// public key keeps its encoding, and so its address
+func newPublicWallet(pubKey []byte) (*Wallet, error) {
+	rawPubKey, err := ParsePubKey(pubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if isLegacyPubKey(pubKey) {
+		return &Wallet{ecdsa.PrivateKey{PublicKey: *rawPubKey}, encodeLegacyPubKey(*rawPubKey)}, nil
+	}
+
+	return &Wallet{ecdsa.PrivateKey{PublicKey: *rawPubKey}, encodePubKey(*rawPubKey)}, nil
 }
 
 // Check if address if valid
 func ValidateAddress(address string) bool {
 	fullPayload := Base58Decode([]byte(address))
+	if len(fullPayload) <= addressChecksumLen {
+		return false
+	}
+
 	actualChecksum := fullPayload[len(fullPayload)-addressChecksumLen:]
 	version := fullPayload[0]
 	pubKeyHash := fullPayload[1 : len(fullPayload)-addressChecksumLen]
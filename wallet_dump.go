@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A wallet dump is a text file with an entry per line, holding every
+// private key in the clear:
+//
+//	seed HEX
+//	nextindex KEYTYPE N
+//	key WIF ADDRESS
+//	script HEX ADDRESS
+//	watch ADDRESS [PUBKEY]
+//	label ADDRESS LABEL
+//	account ADDRESS ACCOUNT
+//
+// Labels and accounts are query escaped. Anything after a # is a comment.
+// Keys derived from the seed are dumped too, so the dump can be imported
+// into a wallet with another seed
+
+// Dump writes the wallet dump of the wallet, which must be unlocked
+func (ws *Wallets) Dump(w io.Writer) error {
+	if ws.IsLocked() {
+		return errWalletLocked
+	}
+
+	fmt.Fprintln(w, "# Wallet dump, every private key is in the clear")
+	if ws.Seed != nil {
+		fmt.Fprintf(w, "seed %x\n", ws.Seed)
+	}
+
+	keyTypes := []KeyType{}
+	for keyType := range ws.NextIndex {
+		keyTypes = append(keyTypes, keyType)
+	}
+	sort.Slice(keyTypes, func(i, j int) bool { return keyTypes[i] < keyTypes[j] })
+
+	for _, keyType := range keyTypes {
+		fmt.Fprintf(w, "nextindex %s %d\n", keyType, ws.NextIndex[keyType])
+	}
+
+	for _, keyType := range keyTypes {
+		account, err := ws.AccountKey(keyType)
+		if err != nil {
+			return err
+		}
+
+		for index := uint32(0); index < ws.NextIndex[keyType]; index++ {
+			key, err := account.Child(index)
+			if err == errInvalidChild {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			wallet := newWalletFromKey(key.PrivateKey())
+			fmt.Fprintf(w, "key %s %s # %s/%d\n", EncodeWIF(wallet.PrivateKey), wallet.GetAddress(), accountPath, index)
+		}
+	}
+
+	imported := []string{}
+	for address := range ws.Wallets {
+		if !ws.derived[address] {
+			imported = append(imported, address)
+		}
+	}
+	sort.Strings(imported)
+
+	for _, address := range imported {
+		wif, err := ws.Wallets[address].WIF()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "key %s %s\n", wif, address)
+	}
+
+	scripts := []string{}
+	for address := range ws.Scripts {
+		scripts = append(scripts, address)
+	}
+	sort.Strings(scripts)
+
+	for _, address := range scripts {
+		fmt.Fprintf(w, "script %x %s # %s\n", []byte(ws.Scripts[address]), address, describeRedeemScript(ws.Scripts[address]))
+	}
+
+	watched := []string{}
+	for address := range ws.Watched {
+		watched = append(watched, address)
+	}
+	sort.Strings(watched)
+
+	for _, address := range watched {
+		if pubKey := ws.Watched[address]; pubKey != nil {
+			fmt.Fprintf(w, "watch %s %x\n", address, pubKey)
+		} else {
+			fmt.Fprintf(w, "watch %s\n", address)
+		}
+	}
+
+	for _, address := range sortedKeys(ws.AllAddresses()) {
+		if label, ok := ws.Labels[address]; ok {
+			fmt.Fprintf(w, "label %s %s\n", address, url.QueryEscape(label))
+		}
+		if account, ok := ws.Accounts[address]; ok {
+			fmt.Fprintf(w, "account %s %s\n", address, url.QueryEscape(account))
+		}
+	}
+
+	return nil
+}
+
+// ImportDump adds the keys, the scripts, the watch-only addresses and the
+// labels of a wallet dump and returns the addresses of the keys. The seed
+// is only taken by a wallet without one, the keys derived from another
+// seed are imported like the others
+func (ws *Wallets) ImportDump(r io.Reader) ([]string, error) {
+	if ws.IsLocked() {
+		return nil, errWalletLocked
+	}
+
+	var seed []byte
+	nextIndex := make(map[KeyType]uint32)
+	addresses := []string{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(strings.SplitN(scanner.Text(), "#", 2)[0])
+		if len(fields) == 0 {
+			continue
+		}
+
+		address, err := ws.importDumpEntry(fields, &seed, nextIndex)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d of the wallet dump: %s", line, err)
+		}
+		if fields[0] == "key" {
+			addresses = append(addresses, address)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if seed != nil && ws.Seed == nil && !ws.IsEncrypted() {
+		ws.Seed = seed
+		ws.NextIndex = nextIndex
+		return addresses, ws.deriveWallets()
+	}
+
+	return addresses, nil
+}
+
+// Imports an entry of a wallet dump and returns its address. The seed and
+// the next indexes are only collected
+func (ws *Wallets) importDumpEntry(fields []string, seed *[]byte, nextIndex map[KeyType]uint32) (string, error) {
+	switch {
+	case fields[0] == "seed" && len(fields) == 2:
+		var err error
+		*seed, err = hex.DecodeString(fields[1])
+
+		return "", err
+
+	case fields[0] == "nextindex" && len(fields) == 3:
+		keyType, err := ParseKeyType(fields[1])
+		if err != nil {
+			return "", err
+		}
+		index, err := strconv.ParseUint(fields[2], 10, 32)
+		nextIndex[keyType] = uint32(index)
+
+		return "", err
+
+	case fields[0] == "key" && len(fields) == 3:
+		privKey, err := DecodeWIF(fields[1])
+		if err != nil {
+			return "", err
+		}
+		// A migrated legacy key is dumped at its legacy address too
+		wallet := newWalletFromKey(privKey)
+		if string(wallet.GetAddress()) != fields[2] && curveKeyType(privKey.Curve) == KeyTypeP256 {
+			wallet = newLegacyWallet(privKey)
+		}
+		if string(wallet.GetAddress()) != fields[2] {
+			return "", fmt.Errorf("Key doesn't match address '%s'", fields[2])
+		}
+
+		return ws.importWallet(wallet), nil
+
+	case fields[0] == "script" && len(fields) == 3:
+		data, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return "", err
+		}
+		redeemScript := Script(data)
+		if ScriptAddress(redeemScript) != fields[2] {
+			return "", fmt.Errorf("Script doesn't match address '%s'", fields[2])
+		}
+		ws.Scripts[fields[2]] = redeemScript
+
+		return fields[2], nil
+
+	case fields[0] == "watch" && (len(fields) == 2 || len(fields) == 3):
+		if _, ok := ws.AllAddresses()[fields[1]]; ok {
+			return fields[1], nil
+		}
+		if len(fields) == 2 {
+			return ws.AddWatchOnly(fields[1], nil)
+		}
+
+		pubKey, err := hex.DecodeString(fields[2])
+		if err != nil {
+			return "", err
+		}
+		address, err := ws.AddWatchOnly("", pubKey)
+		if err == nil && address != fields[1] {
+			return "", fmt.Errorf("Public key doesn't match address '%s'", fields[1])
+		}
+
+		return address, err
+
+	case (fields[0] == "label" || fields[0] == "account") && len(fields) == 3:
+		name, err := url.QueryUnescape(fields[2])
+		if err != nil {
+			return "", err
+		}
+		if fields[0] == "label" {
+			return fields[1], ws.SetLabel(fields[1], name)
+		}
+
+		return fields[1], ws.SetAccount(fields[1], name)
+	}
+
+	return "", fmt.Errorf("Invalid entry '%s'", strings.Join(fields, " "))
+}
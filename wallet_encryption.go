@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// File holding the key of an unlocked wallet until it expires
+const walletUnlockFile = "wallet.unlock"
+
+// scrypt cost parameters of newly encrypted wallets
+const scryptN, scryptR, scryptP = 32768, 8, 1
+
+var errWalletLocked = errors.New("Wallet is locked, unlock it with walletpassphrase")
+var errWrongPassphrase = errors.New("Wrong wallet passphrase")
+
+// walletEncryption is how an encrypted wallet file stores the private keys.
+// The public parts stay readable, so a locked wallet still knows its
+// addresses and derives new ones
+type walletEncryption struct {
+	// Salt and cost of the scrypt key derivation from the passphrase
+	Salt    []byte
+	N, R, P int
+	// AES-GCM sealed walletSecrets, after their nonce
+	Secrets []byte
+	// Account extended public keys by key type
+	AccountKeys map[KeyType]string
+	// Public keys of the keys that aren't derived from the seed, by address
+	PubKeys map[string][]byte
+}
+
+// walletSecrets is the private key material of an encrypted wallet
+type walletSecrets struct {
+	Seed    []byte
+	Wallets map[string]*Wallet
+}
+
+// Derives the AES key of a wallet from its passphrase
+func (e walletEncryption) deriveKey(passphrase string) []byte {
+	key, err := scrypt.Key([]byte(passphrase), e.Salt, e.N, e.R, e.P, 32)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return key
+}
+
+// Encrypts secrets with key, the random nonce coming first
+func sealWalletSecrets(key []byte, secrets walletSecrets) []byte {
+	plaintext := bytes.Buffer{}
+	err := gob.NewEncoder(&plaintext).Encode(secrets)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	aead := newWalletCipher(key)
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext.Bytes(), nil)
+}
+
+// Decrypts secrets sealed by sealWalletSecrets, failing with
+// errWrongPassphrase if key can't authenticate them
+func openWalletSecrets(key, sealed []byte) (walletSecrets, error) {
+	secrets := walletSecrets{}
+	aead := newWalletCipher(key)
+	if len(sealed) < aead.NonceSize() {
+		return secrets, errors.New("Encrypted wallet secrets are truncated")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return secrets, errWrongPassphrase
+	}
+
+	err = gob.NewDecoder(bytes.NewReader(plaintext)).Decode(&secrets)
+
+	return secrets, err
+}
+
+func newWalletCipher(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return aead
+}
+
+// IsEncrypted checks if the private keys are encrypted with a passphrase
+func (ws *Wallets) IsEncrypted() bool {
+	return ws.encryption != nil
+}
+
+// IsLocked checks if the wallet is encrypted and its private keys aren't
+// available
+func (ws *Wallets) IsLocked() bool {
+	return ws.encryption != nil && ws.key == nil
+}
+
+// Encrypt encrypts the private keys with a key derived from passphrase
+// when the wallet is saved. The wallet stays unlocked until it is loaded
+// again. A seed is made first if there isn't one, so the locked wallet can
+// still derive addresses
+func (ws *Wallets) Encrypt(passphrase string) error {
+	if ws.IsEncrypted() {
+		return errors.New("The wallet is already encrypted")
+	}
+	if passphrase == "" {
+		return errors.New("The passphrase can't be empty")
+	}
+
+	if ws.Seed == nil {
+		ws.newSeed()
+	}
+
+	encryption := walletEncryption{make([]byte, 16), scryptN, scryptR, scryptP, nil, make(map[KeyType]string), nil}
+	_, err := rand.Read(encryption.Salt)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	for keyType := range keyTypeNames {
+		account, err := ws.AccountKey(keyType)
+		if err != nil {
+			return err
+		}
+		encryption.AccountKeys[keyType] = account.Neuter().String()
+	}
+
+	ws.encryption = &encryption
+	ws.key = encryption.deriveKey(passphrase)
+
+	return nil
+}
+
+// Unlock decrypts the private keys with passphrase and returns the key
+// they were encrypted with
+func (ws *Wallets) Unlock(passphrase string) ([]byte, error) {
+	if !ws.IsEncrypted() {
+		return nil, errors.New("The wallet isn't encrypted")
+	}
+
+	key := ws.encryption.deriveKey(passphrase)
+
+	return key, ws.unlockWithKey(key)
+}
+
+// Decrypts the private keys with the key derived from the passphrase
+func (ws *Wallets) unlockWithKey(key []byte) error {
+	secrets, err := openWalletSecrets(key, ws.encryption.Secrets)
+	if err != nil {
+		return err
+	}
+
+	ws.Seed = secrets.Seed
+	ws.key = key
+	for address, wallet := range secrets.Wallets {
+		ws.Wallets[address] = wallet
+	}
+
+	return ws.deriveWallets()
+}
+
+// Moves the seed and the keys of the wallet file content of an encrypted
+// wallet into its sealed secrets. They are sealed again when they are
+// available, they don't change while locked
+func (ws Wallets) encryptedContent(content walletFileContent) walletFileContent {
+	encryption := *ws.encryption
+
+	if ws.key != nil {
+		encryption.PubKeys = make(map[string][]byte)
+		for address, wallet := range content.Wallets {
+			encryption.PubKeys[address] = wallet.PublicKey
+		}
+		encryption.Secrets = sealWalletSecrets(ws.key, walletSecrets{content.Seed, content.Wallets})
+	}
+
+	content.Seed = nil
+	content.Wallets = nil
+	content.Encryption = &encryption
+
+	return content
+}
+
+// Fills Wallets with the public keys of a locked wallet
+func (ws *Wallets) loadLocked() error {
+	for address, pubKey := range ws.encryption.PubKeys {
+		wallet, err := newPublicWallet(pubKey)
+		if err != nil {
+			return err
+		}
+		ws.Wallets[address] = wallet
+	}
+
+	return ws.deriveWallets()
+}
+
+// unlockSession is the key of an unlocked wallet and when it expires
+type unlockSession struct {
+	Key     []byte
+	Expires int64
+}
+
+// Saves the key of an unlocked wallet for the commands run until expires
+func saveUnlockSession(key []byte, expires time.Time) {
+	content := bytes.Buffer{}
+	err := gob.NewEncoder(&content).Encode(unlockSession{key, expires.Unix()})
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = ioutil.WriteFile(walletUnlockFile, content.Bytes(), 0600)
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// Returns the key saved by walletpassphrase, or nil once it has expired
+func loadUnlockSession() []byte {
+	fileContent, err := ioutil.ReadFile(walletUnlockFile)
+	if err != nil {
+		return nil
+	}
+
+	session := unlockSession{}
+	err = gob.NewDecoder(bytes.NewReader(fileContent)).Decode(&session)
+	if err != nil || time.Now().Unix() >= session.Expires {
+		removeUnlockSession()
+		return nil
+	}
+
+	return session.Key
+}
+
+// Forgets the key of an unlocked wallet
+func removeUnlockSession() {
+	err := os.Remove(walletUnlockFile)
+	if err != nil && !os.IsNotExist(err) {
+		log.Panic(err)
+	}
+}
@@ -2,40 +2,262 @@ package main
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/gob"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/big"
 	"os"
+	"sort"
+	"strings"
 )
 
 const walletFile = "wallet.dat"
+const walletBackupFile = walletFile + ".bak"
+const seedLen = 32
+const defaultAccount = "default"
+
+// Path of the account key that wallet keys are derived under, as its
+// non-hardened children m/0'/i. The extended public key of the account
+// derives the same addresses without the private keys
+const accountPath = "m/0'"
 
 type Wallets struct {
+	// Keys by address, derived from the seed or not
 	Wallets map[string]*Wallet
+	// Redeem scripts of multisig addresses the wallet takes part in
+	Scripts map[string]Script
+	// Watch-only addresses, with their public key when it is known
+	Watched map[string][]byte
+	// Labels and account names of addresses, addresses without an account
+	// are in the default one
+	Labels   map[string]string
+	Accounts map[string]string
+	// Seed the keys are derived from, and index of the next key of each
+	// key type
+	Seed      []byte
+	NextIndex map[KeyType]uint32
+	// Addresses of the keys derived from the seed
+	derived map[string]bool
+	// Encryption of the private keys, and its key once unlocked
+	encryption *walletEncryption
+	key        []byte
+}
+
+// Layout of the wallet file. Keys derived from the seed aren't saved, they
+// are derived again when the file is loaded. Files saved before the seed
+// existed have the same layout without one. Encrypted wallets keep the seed
+// and the keys in Encryption instead
+type walletFileContent struct {
+	Seed      []byte
+	NextIndex map[KeyType]uint32
+	// Keys that aren't derived from the seed
+	Wallets    map[string]*Wallet
+	Scripts    map[string]Script
+	Encryption *walletEncryption
+	Watched    map[string][]byte
+	Labels     map[string]string
+	Accounts   map[string]string
 }
 
 // Creates Wallets and fill them from a file if it exists
 func NewWallets() (*Wallets, error) {
+	wallets := newWallets()
+	err := wallets.LoadFromFile()
+
+	return wallets, err
+}
+
+// Creates empty Wallets
+func newWallets() *Wallets {
 	wallets := Wallets{}
 	wallets.Wallets = make(map[string]*Wallet)
+	wallets.Scripts = make(map[string]Script)
+	wallets.Watched = make(map[string][]byte)
+	wallets.Labels = make(map[string]string)
+	wallets.Accounts = make(map[string]string)
+	wallets.NextIndex = make(map[KeyType]uint32)
+	wallets.derived = make(map[string]bool)
 
-	err := wallets.LoadFromFile()
+	return &wallets
+}
+
+// Derives the next key of the given type from the seed, adds its Wallet
+// to Wallets and returns its address. The seed is made on first use. A
+// locked wallet derives only the public key
+func (ws *Wallets) CreateWallet(keyType KeyType) string {
+	if ws.Seed == nil && !ws.IsEncrypted() {
+		ws.newSeed()
+	}
 
-	return &wallets, err
+	account, err := ws.AccountKey(keyType)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	for {
+		index := ws.NextIndex[keyType]
+		ws.NextIndex[keyType]++
+
+		// An index without a valid key is skipped
+		key, err := account.Child(index)
+		if err == errInvalidChild {
+			continue
+		}
+		if err != nil {
+			log.Panic(err)
+		}
+
+		return ws.addDerived(key)
+	}
+}
+
+// ImportKey adds a private key that isn't derived from the seed and
+// returns its address. An encrypted wallet must be unlocked
+func (ws *Wallets) ImportKey(privKey ecdsa.PrivateKey) (string, error) {
+	if ws.IsLocked() {
+		return "", errWalletLocked
+	}
+
+	return ws.importWallet(newWalletFromKey(privKey)), nil
 }
 
-// Adds a newly created Wallet to Wallets
-func (ws *Wallets) CreateWallet() string {
-	wallet := NewWallet()
+// Adds the Wallet of an imported key and returns its address, which is no
+// longer watch-only
+func (ws *Wallets) importWallet(wallet *Wallet) string {
+	address := string(wallet.GetAddress())
+	if !ws.derived[address] {
+		ws.Wallets[address] = wallet
+	}
+	delete(ws.Watched, address)
+
+	return address
+}
+
+// Makes a random seed
+func (ws *Wallets) newSeed() {
+	ws.Seed = make([]byte, seedLen)
+	_, err := rand.Read(ws.Seed)
+	if err != nil {
+		log.Panic(err)
+	}
+}
+
+// SetSeed sets the seed the keys are derived from, for a wallet that
+// doesn't have one yet
+func (ws *Wallets) SetSeed(seed []byte) error {
+	if ws.Seed != nil || ws.IsEncrypted() {
+		return errors.New("The wallet already has a seed")
+	}
+
+	ws.Seed = seed
+
+	return nil
+}
+
+// AccountKey returns the extended key the keys of keyType are derived from,
+// which is the extended public key when the wallet is locked
+func (ws *Wallets) AccountKey(keyType KeyType) (*ExtendedKey, error) {
+	if ws.IsLocked() {
+		return ParseExtendedKey(ws.encryption.AccountKeys[keyType])
+	}
+	if ws.Seed == nil {
+		return nil, errors.New("The wallet has no seed yet, create an address first")
+	}
+
+	master, err := NewMasterKey(ws.Seed, keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	return master.Derive(accountPath)
+}
+
+// Adds the Wallet of a key derived from the seed and returns its address
+func (ws *Wallets) addDerived(key *ExtendedKey) string {
+	wallet, err := newPublicWallet(key.PubKey())
+	if err != nil {
+		log.Panic(err)
+	}
+	if key.Private {
+		wallet = newWalletFromKey(key.PrivateKey())
+	}
 	address := fmt.Sprintf("%s", wallet.GetAddress())
 
 	ws.Wallets[address] = wallet
+	ws.derived[address] = true
 
 	return address
 }
 
+// Rescan derives the keys of every type until gapLimit keys in a row have
+// never received an output, used holding the hex pubkey hashes outputs were
+// locked with. Keys up to the last used one are added, and their number
+// is returned
+func (ws *Wallets) Rescan(used map[string]bool, gapLimit int) (int, error) {
+	found := 0
+
+	for keyType := range keyTypeNames {
+		account, err := ws.AccountKey(keyType)
+		if err != nil {
+			return 0, err
+		}
+
+		next := uint32(0)
+		for index, gap := uint32(0), 0; gap < gapLimit; index++ {
+			key, err := account.Child(index)
+			if err == errInvalidChild {
+				continue
+			}
+			if err != nil {
+				return 0, err
+			}
+
+			gap++
+			if used[hex.EncodeToString(HashPubKey(key.PubKey()))] {
+				next = index + 1
+				gap = 0
+				found++
+			}
+		}
+
+		if next > ws.NextIndex[keyType] {
+			ws.NextIndex[keyType] = next
+		}
+	}
+
+	return found, ws.deriveWallets()
+}
+
+// Derives again the keys created from the seed
+func (ws *Wallets) deriveWallets() error {
+	for keyType, next := range ws.NextIndex {
+		account, err := ws.AccountKey(keyType)
+		if err != nil {
+			return err
+		}
+
+		for index := uint32(0); index < next; index++ {
+			key, err := account.Child(index)
+			if err == errInvalidChild {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			ws.addDerived(key)
+		}
+	}
+
+	return nil
+}
+
 // Loads wallets from the file
 func (ws *Wallets) LoadFromFile() error {
 	if _, err := os.Stat(walletFile); os.IsNotExist(err) {
@@ -47,32 +269,166 @@ func (ws *Wallets) LoadFromFile() error {
 		log.Panic(err)
 	}
 
-	wallets := Wallets{}
-	gob.Register(elliptic.P256())
+	content := walletFileContent{}
 	decoder := gob.NewDecoder(bytes.NewReader(fileContent))
-	err = decoder.Decode(&wallets)
+	err = decoder.Decode(&content)
+	migrated := false
+	if err != nil {
+		// Only a file in the legacy layout is upgraded, any other can't be read
+		var legacyErr error
+		content, legacyErr = migrateLegacyWallets(fileContent)
+		if legacyErr != nil {
+			log.Panicf("ERROR: Can't read %s: %s", walletFile, err)
+		}
+		migrated = true
+	}
+
+	err = ws.load(content)
 	if err != nil {
 		log.Panic(err)
 	}
 
-	ws.Wallets = wallets.Wallets
+	if key := loadUnlockSession(); key != nil && ws.IsLocked() {
+		err = ws.unlockWithKey(key)
+		if err != nil {
+			log.Panic(err)
+		}
+	}
+
+	if migrated {
+		backupWalletFile(fileContent)
+		ws.SaveToFile()
+		fmt.Printf("Wallet file upgraded, new addresses use compressed public keys. The original file is kept in %s\n", walletBackupFile)
+	}
 
 	return nil
 }
 
+// Fills Wallets from the content of a wallet file
+func (ws *Wallets) load(content walletFileContent) error {
+	ws.Seed = content.Seed
+	if content.NextIndex != nil {
+		ws.NextIndex = content.NextIndex
+	}
+	if content.Scripts != nil {
+		ws.Scripts = content.Scripts
+	}
+	if content.Watched != nil {
+		ws.Watched = content.Watched
+	}
+	if content.Labels != nil {
+		ws.Labels = content.Labels
+	}
+	if content.Accounts != nil {
+		ws.Accounts = content.Accounts
+	}
+	for address, wallet := range content.Wallets {
+		ws.Wallets[address] = wallet
+	}
+
+	if content.Encryption != nil {
+		ws.encryption = content.Encryption
+		return ws.loadLocked()
+	}
+
+	return ws.deriveWallets()
+}
+
+// Returns the content of the wallet file, without the derived keys
+func (ws Wallets) fileContent() walletFileContent {
+	content := walletFileContent{ws.Seed, ws.NextIndex, make(map[string]*Wallet), ws.Scripts, nil, ws.Watched, ws.Labels, ws.Accounts}
+	for address, wallet := range ws.Wallets {
+		if !ws.derived[address] {
+			content.Wallets[address] = wallet
+		}
+	}
+
+	if ws.IsEncrypted() {
+		return ws.encryptedContent(content)
+	}
+
+	return content
+}
+
 // Saves wallets to a file
 func (ws Wallets) SaveToFile() {
 	content := bytes.Buffer{}
+	encoder := gob.NewEncoder(&content)
+	err := encoder.Encode(ws.fileContent())
+	if err != nil {
+		log.Panic(err)
+	}
 
-	gob.Register(elliptic.P256())
+	err = ioutil.WriteFile(walletFile, content.Bytes(), 0600)
+	if err != nil {
+		log.Panic(err)
+	}
 
-	encoder := gob.NewEncoder(&content)
-	err := encoder.Encode(ws)
+	// WriteFile keeps the mode of an existing file, which older versions
+	// left readable by everyone
+	err = os.Chmod(walletFile, 0600)
 	if err != nil {
 		log.Panic(err)
 	}
+}
 
-	err = ioutil.WriteFile(walletFile, content.Bytes(), 0644)
+// Layout of wallets saved before keys had a serialized form of their own,
+// without the curve that can't be decoded anymore
+type legacyWallets struct {
+	Wallets map[string]*struct {
+		PrivateKey struct {
+			D *big.Int
+		}
+	}
+	Scripts map[string]Script
+}
+
+// Decodes a wallet file in the legacy layout. Every key gets an address
+// derived from its compressed public key, and keeps its legacy address so
+// the outputs locked to it can still be spent
+func migrateLegacyWallets(fileContent []byte) (walletFileContent, error) {
+	legacy := legacyWallets{}
+	decoder := gob.NewDecoder(bytes.NewReader(fileContent))
+	err := decoder.Decode(&legacy)
+	if err != nil {
+		return walletFileContent{}, err
+	}
+	if len(legacy.Wallets) == 0 {
+		return walletFileContent{}, errors.New("Not a legacy wallet file")
+	}
+
+	wallets := walletFileContent{nil, nil, make(map[string]*Wallet), legacy.Scripts, nil, nil, nil, nil}
+	if wallets.Scripts == nil {
+		wallets.Scripts = make(map[string]Script)
+	}
+
+	for _, legacyWallet := range legacy.Wallets {
+		if legacyWallet == nil || legacyWallet.PrivateKey.D == nil {
+			return walletFileContent{}, errors.New("Legacy wallet without a private key")
+		}
+
+		privKey := newPrivateKey(elliptic.P256(), legacyWallet.PrivateKey.D.Bytes())
+		for _, wallet := range []*Wallet{newWalletFromKey(privKey), newLegacyWallet(privKey)} {
+			wallets.Wallets[string(wallet.GetAddress())] = wallet
+		}
+	}
+
+	return wallets, nil
+}
+
+// Copies the content of a wallet file about to be upgraded to the backup
+// file, which mustn't exist yet
+func backupWalletFile(fileContent []byte) {
+	file, err := os.OpenFile(walletBackupFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		log.Panicf("ERROR: %s already exists, move it away to upgrade %s", walletBackupFile, walletFile)
+	}
+	if err != nil {
+		log.Panic(err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(fileContent)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -89,7 +445,216 @@ func (ws *Wallets) GetAddresses() []string {
 	return addresses
 }
 
+// Adds an M-of-N multisig redeem script and returns its address. Keys are
+// sorted, so every participant gets the same address
+func (ws *Wallets) AddMultisig(m int, pubKeys [][]byte) string {
+	pubKeys = append([][]byte{}, pubKeys...)
+	sort.Slice(pubKeys, func(i, j int) bool {
+		return bytes.Compare(pubKeys[i], pubKeys[j]) < 0
+	})
+
+	redeemScript := NewMultisigScript(m, pubKeys)
+	address := ScriptAddress(redeemScript)
+	ws.Scripts[address] = redeemScript
+
+	return address
+}
+
+// AddTimelock adds a P2SH address paying to pubKeyHash once lockTime has
+// elapsed and returns it
+func (ws *Wallets) AddTimelock(lockTime int, relative bool, pubKeyHash []byte) string {
+	redeemScript := NewTimelockScript(lockTime, relative, pubKeyHash)
+	address := ScriptAddress(redeemScript)
+	ws.Scripts[address] = redeemScript
+
+	return address
+}
+
+// AddHTLC adds the P2SH address of a hash time-locked contract and
+// returns it
+func (ws *Wallets) AddHTLC(h HTLC) string {
+	redeemScript := h.Script()
+	address := ScriptAddress(redeemScript)
+	ws.Scripts[address] = redeemScript
+
+	return address
+}
+
+// Returns the redeem script hashing to scriptHash, or nil
+func (ws Wallets) GetScript(scriptHash []byte) Script {
+	for _, script := range ws.Scripts {
+		if bytes.Equal(HashPubKey(script), scriptHash) {
+			return script
+		}
+	}
+
+	return nil
+}
+
+// FundingAddresses fills in the defaults for spending from the wallet:
+// every address when from is empty, and the first from address as change.
+// Addresses listed twice are kept once, so their outputs aren't picked twice
+func (ws *Wallets) FundingAddresses(from []string, change string) ([]string, string) {
+	if len(from) == 0 {
+		from = ws.GetAddresses()
+		sort.Strings(from)
+	}
+
+	listed := make(map[string]bool)
+	unique := []string{}
+	for _, address := range from {
+		if !listed[address] {
+			listed[address] = true
+			unique = append(unique, address)
+		}
+	}
+	from = unique
+	if len(from) == 0 {
+		log.Panic("ERROR: There are no addresses to spend from")
+	}
+	if change == "" {
+		change = from[0]
+	}
+
+	return from, change
+}
+
+// AddWatchOnly adds an address to follow without its private key, from
+// the address or from the public key, and returns it
+func (ws *Wallets) AddWatchOnly(address string, pubKey []byte) (string, error) {
+	if pubKey != nil {
+		rawPubKey, err := ParsePubKey(pubKey)
+		if err != nil {
+			return "", err
+		}
+		pubKey = encodePubKey(*rawPubKey)
+		address = string(encodeAddress(version, HashPubKey(pubKey)))
+	} else if len(Base58Decode([]byte(address))) <= addressChecksumLen || !ValidateAddress(address) {
+		return "", fmt.Errorf("Address '%s' is not valid", address)
+	}
+
+	if _, ok := ws.AllAddresses()[address]; ok {
+		return "", fmt.Errorf("Address '%s' is already in the wallet", address)
+	}
+	ws.Watched[address] = pubKey
+
+	return address, nil
+}
+
+// AllAddresses returns every address the wallet follows: the addresses of
+// its keys and redeem scripts, and the watch-only ones. The value tells if
+// the address is watch-only
+func (ws Wallets) AllAddresses() map[string]bool {
+	addresses := make(map[string]bool)
+	for address := range ws.Wallets {
+		addresses[address] = false
+	}
+	for address := range ws.Scripts {
+		addresses[address] = false
+	}
+	for address := range ws.Watched {
+		addresses[address] = true
+	}
+
+	return addresses
+}
+
+// SpendingWallet returns the Wallet of an address to spend from, failing
+// for watch-only addresses
+func (ws Wallets) SpendingWallet(address string) (*Wallet, error) {
+	if wallet := ws.Wallets[address]; wallet != nil {
+		return wallet, nil
+	}
+	if _, ok := ws.Watched[address]; ok {
+		return nil, fmt.Errorf("Address '%s' is watch-only, the wallet has no key to spend from it", address)
+	}
+
+	return nil, fmt.Errorf("Address '%s' is not in the wallet", address)
+}
+
+// SetLabel sets the label of a wallet address, an empty label removes it
+func (ws *Wallets) SetLabel(address, label string) error {
+	if _, ok := ws.AllAddresses()[address]; !ok {
+		return fmt.Errorf("Address '%s' is not in the wallet", address)
+	}
+
+	if label == "" {
+		delete(ws.Labels, address)
+	} else {
+		ws.Labels[address] = label
+	}
+
+	return nil
+}
+
+// SetAccount moves a wallet address to the named account
+func (ws *Wallets) SetAccount(address, account string) error {
+	if _, ok := ws.AllAddresses()[address]; !ok {
+		return fmt.Errorf("Address '%s' is not in the wallet", address)
+	}
+	if strings.TrimSpace(account) != account {
+		return errors.New("Account names can't start or end with spaces")
+	}
+
+	if account == "" || account == defaultAccount {
+		delete(ws.Accounts, address)
+	} else {
+		ws.Accounts[address] = account
+	}
+
+	return nil
+}
+
+// Account returns the name of the account of an address
+func (ws Wallets) Account(address string) string {
+	if account, ok := ws.Accounts[address]; ok {
+		return account
+	}
+
+	return defaultAccount
+}
+
+// AccountGroup is an account with its addresses
+type AccountGroup struct {
+	Name      string
+	Addresses []string
+}
+
+// AccountGroups groups the wallet addresses by account, the default account
+// first and the others by name, with sorted addresses
+func (ws Wallets) AccountGroups() []AccountGroup {
+	accounts := make(map[string][]string)
+	for _, address := range sortedKeys(ws.AllAddresses()) {
+		account := ws.Account(address)
+		accounts[account] = append(accounts[account], address)
+	}
+
+	groups := []AccountGroup{}
+	for name, addresses := range accounts {
+		groups = append(groups, AccountGroup{name, addresses})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Name == defaultAccount || groups[j].Name == defaultAccount {
+			return groups[i].Name == defaultAccount && groups[j].Name != defaultAccount
+		}
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups
+}
+
 // Returns a Wallet by its address
 func (ws Wallets) GetWallet(address string) Wallet {
 	return *ws.Wallets[address]
 }
+
+// Returns the Wallet whose public key hashes to pubKeyHash, or nil
+func (ws Wallets) GetWalletByPubKeyHash(pubKeyHash []byte) *Wallet {
+	for _, wallet := range ws.Wallets {
+		if bytes.Equal(HashPubKey(wallet.PublicKey), pubKeyHash) {
+			return wallet
+		}
+	}
+
+	return nil
+}
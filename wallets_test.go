@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type legacyTestCurve struct {
+	Name string
+}
+
+// Encodes the keys in the wallet file layout of the first releases
+func legacyWalletFile(t *testing.T, privKeys ...ecdsa.PrivateKey) []byte {
+	// Same layout as a gob encoded ecdsa.PrivateKey, curve included
+	type legacyPublicKey struct {
+		Curve interface{}
+		X, Y  *big.Int
+	}
+	type legacyPrivateKey struct {
+		PublicKey legacyPublicKey
+		D         *big.Int
+	}
+	type legacyWallet struct {
+		PrivateKey legacyPrivateKey
+		PublicKey  []byte
+	}
+	type legacyFile struct {
+		Wallets map[string]*legacyWallet
+		Scripts map[string]Script
+	}
+
+	gob.Register(legacyTestCurve{})
+	file := legacyFile{map[string]*legacyWallet{}, nil}
+	for _, privKey := range privKeys {
+		pubKey := encodeLegacyPubKey(privKey.PublicKey)
+		file.Wallets[string(encodeAddress(version, HashPubKey(pubKey)))] = &legacyWallet{
+			legacyPrivateKey{legacyPublicKey{legacyTestCurve{"P-256"}, privKey.X, privKey.Y}, privKey.D},
+			pubKey,
+		}
+	}
+
+	content := bytes.Buffer{}
+	assert.Nil(t, gob.NewEncoder(&content).Encode(file))
+
+	return content.Bytes()
+}
+
+func TestMigrateLegacyWallets(t *testing.T) {
+	privKey, _ := newKeyPair(elliptic.P256())
+	wallets, err := migrateLegacyWallets(legacyWalletFile(t, privKey))
+	assert.Nil(t, err)
+
+	// The key keeps its legacy address next to the new one
+	expected := newWalletFromKey(privKey)
+	address := string(expected.GetAddress())
+	legacy := newLegacyWallet(privKey)
+	legacyAddress := string(legacy.GetAddress())
+
+	assert.Equal(t, 2, len(wallets.Wallets))
+	assert.Equal(t, expected.PublicKey, wallets.Wallets[address].PublicKey)
+	assert.Equal(t, privKey.D, wallets.Wallets[address].PrivateKey.D)
+	assert.Equal(t, append(privKey.X.Bytes(), privKey.Y.Bytes()...), wallets.Wallets[legacyAddress].PublicKey)
+	assert.True(t, wallets.Wallets[legacyAddress].IsLegacy())
+	assert.NotNil(t, wallets.Scripts)
+
+	// Saving the wallet keeps the legacy address
+	content := bytes.Buffer{}
+	assert.Nil(t, gob.NewEncoder(&content).Encode(wallets))
+	decoded := walletFileContent{}
+	assert.Nil(t, gob.NewDecoder(&content).Decode(&decoded))
+	assert.True(t, decoded.Wallets[legacyAddress].IsLegacy())
+	assert.Equal(t, legacy.PublicKey, decoded.Wallets[legacyAddress].PublicKey)
+
+	_, err = migrateLegacyWallets([]byte("not a wallet"))
+	assert.NotNil(t, err)
+}
+
+func TestMigrateLegacyWalletFile(t *testing.T) {
+	privKey, _ := newKeyPair(elliptic.P256())
+	legacyAddress := string(newLegacyWallet(privKey).GetAddress())
+	bc := newTestBlockchain(t, newLegacyWallet(privKey))
+
+	legacyFile := legacyWalletFile(t, privKey)
+	assert.Nil(t, ioutil.WriteFile(walletFile, legacyFile, 0600))
+	wallets, err := NewWallets()
+	assert.Nil(t, err)
+
+	backup, err := ioutil.ReadFile(walletBackupFile)
+	assert.Nil(t, err)
+	assert.Equal(t, legacyFile, backup)
+	info, err := os.Stat(walletBackupFile)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// The coins at the legacy address are still spendable
+	change := string(newWalletFromKey(privKey).GetAddress())
+	selector, err := NewCoinSelector("largest")
+	assert.Nil(t, err)
+	tx := NewWalletTransaction(wallets, []string{legacyAddress}, change, []Recipient{{change, subsidy}}, selector, 0, &UTXOSet{bc})
+	assert.Nil(t, bc.VerifyTransactions([]*Transaction{tx}))
+	bc.MineBlock([]*Transaction{NewCoinbaseTX(change, ""), tx})
+
+	// The upgraded file loads as is
+	reloaded, err := NewWallets()
+	assert.Nil(t, err)
+	assert.True(t, reloaded.Wallets[legacyAddress].IsLegacy())
+}
+
+func TestSaveWalletFileMode(t *testing.T) {
+	chdirTemp(t)
+	assert.Nil(t, ioutil.WriteFile(walletFile, nil, 0644))
+
+	newWallets().SaveToFile()
+	info, err := os.Stat(walletFile)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestLoadCorruptedWalletFile(t *testing.T) {
+	chdirTemp(t)
+	wallets := newWallets()
+	wallets.CreateWallet(KeyTypeP256)
+	wallets.SaveToFile()
+
+	content, err := ioutil.ReadFile(walletFile)
+	assert.Nil(t, err)
+	corrupted := content[:len(content)/2]
+	assert.Nil(t, ioutil.WriteFile(walletFile, corrupted, 0600))
+
+	// A damaged file is reported, not mistaken for a legacy one
+	assert.Panics(t, func() { NewWallets() })
+	_, err = os.Stat(walletBackupFile)
+	assert.True(t, os.IsNotExist(err))
+	content, err = ioutil.ReadFile(walletFile)
+	assert.Nil(t, err)
+	assert.Equal(t, corrupted, content)
+}
+
+func TestWalletGobRoundTrip(t *testing.T) {
+	wallets := map[string]*Wallet{}
+	for keyType := range keyTypeNames {
+		wallet := NewWallet(keyType)
+		wallets[string(wallet.GetAddress())] = wallet
+	}
+
+	content := bytes.Buffer{}
+	assert.Nil(t, gob.NewEncoder(&content).Encode(wallets))
+
+	decoded := map[string]*Wallet{}
+	assert.Nil(t, gob.NewDecoder(&content).Decode(&decoded))
+	for address, wallet := range wallets {
+		assert.Equal(t, wallet.KeyType(), decoded[address].KeyType())
+		assert.Equal(t, wallet.PublicKey, decoded[address].PublicKey)
+		assert.Equal(t, wallet.PrivateKey.D, decoded[address].PrivateKey.D)
+	}
+
+	// Wallets saved before key types existed hold bare P-256 scalars
+	privKey, pubKey := newKeyPair(elliptic.P256())
+	wallet := Wallet{}
+	assert.Nil(t, wallet.GobDecode(privKey.D.FillBytes(make([]byte, 32))))
+	assert.Equal(t, KeyTypeP256, wallet.KeyType())
+	assert.Equal(t, pubKey, wallet.PublicKey)
+}
+
+func TestWalletsDerivation(t *testing.T) {
+	wallets := newWallets()
+	imported := NewWallet(KeyTypeP256)
+	wallets.Wallets[string(imported.GetAddress())] = imported
+
+	addresses := []string{}
+	for keyType := range keyTypeNames {
+		addresses = append(addresses, wallets.CreateWallet(keyType), wallets.CreateWallet(keyType))
+	}
+
+	// Only the key that isn't derived from the seed is saved
+	content := wallets.fileContent()
+	assert.Equal(t, seedLen, len(content.Seed))
+	assert.Equal(t, 1, len(content.Wallets))
+
+	restored := newWallets()
+	assert.Nil(t, restored.load(content))
+	assert.Equal(t, len(wallets.Wallets), len(restored.Wallets))
+	for _, address := range addresses {
+		assert.Equal(t, wallets.Wallets[address].PrivateKey.D, restored.Wallets[address].PrivateKey.D)
+	}
+
+	// The account extended public key derives the same addresses
+	for keyType := range keyTypeNames {
+		account, err := wallets.AccountKey(keyType)
+		assert.Nil(t, err)
+
+		xpub, err := ParseExtendedKey(account.Neuter().String())
+		assert.Nil(t, err)
+		for index := uint32(0); index < 2; index++ {
+			key, err := xpub.Child(index)
+			assert.Nil(t, err)
+			assert.NotNil(t, wallets.Wallets[string(encodeAddress(version, HashPubKey(key.PubKey())))])
+		}
+	}
+}
+
+func TestFundingAddresses(t *testing.T) {
+	wallets := newWallets()
+	a := wallets.CreateWallet(KeyTypeP256)
+	b := wallets.CreateWallet(KeyTypeP256)
+
+	from, change := wallets.FundingAddresses([]string{a, b, a}, "")
+	assert.Equal(t, []string{a, b}, from, "Addresses listed twice are kept once")
+	assert.Equal(t, a, change)
+
+	from, change = wallets.FundingAddresses(nil, b)
+	assert.Equal(t, 2, len(from))
+	assert.Equal(t, b, change)
+}
+
+func TestWalletsRescan(t *testing.T) {
+	seed := MnemonicSeed("legal winner thank year wave sausage worth useful legal winner thank yellow", "")
+	wallets := newWallets()
+	assert.Nil(t, wallets.SetSeed(seed))
+	assert.NotNil(t, wallets.SetSeed(seed))
+
+	addresses := []string{}
+	for i := 0; i < 5; i++ {
+		addresses = append(addresses, wallets.CreateWallet(KeyTypeP256))
+	}
+	used := map[string]bool{}
+	for _, i := range []int{0, 3} {
+		pubKeyHash := HashPubKey(wallets.Wallets[addresses[i]].PublicKey)
+		used[hex.EncodeToString(pubKeyHash)] = true
+	}
+
+	// The rescan stops after gapLimit unused addresses
+	restored := newWallets()
+	restored.SetSeed(seed)
+	found, err := restored.Rescan(used, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, found)
+	assert.Equal(t, 1, len(restored.Wallets))
+
+	restored = newWallets()
+	restored.SetSeed(seed)
+	found, err = restored.Rescan(used, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, found)
+	assert.Equal(t, uint32(4), restored.NextIndex[KeyTypeP256])
+	for _, address := range addresses[:4] {
+		assert.NotNil(t, restored.Wallets[address])
+	}
+}
+
+func TestWalletsEncryption(t *testing.T) {
+	wallets := newWallets()
+	imported := NewWallet(KeyTypeSecp256k1)
+	wallets.Wallets[string(imported.GetAddress())] = imported
+	derived := wallets.CreateWallet(KeyTypeP256)
+
+	assert.Nil(t, wallets.Encrypt("secret"))
+	assert.NotNil(t, wallets.Encrypt("secret"))
+
+	// No private key is saved in the clear
+	content := wallets.fileContent()
+	assert.Nil(t, content.Seed)
+	assert.Nil(t, content.Wallets)
+
+	// A locked wallet knows its addresses and derives new ones, but can't sign
+	locked := newWallets()
+	assert.Nil(t, locked.load(content))
+	assert.True(t, locked.IsLocked())
+	assert.Equal(t, len(wallets.Wallets), len(locked.Wallets))
+	_, err := locked.Wallets[derived].Signer()
+	assert.Equal(t, errWalletLocked, err)
+	_, err = locked.Wallets[string(imported.GetAddress())].Signer()
+	assert.Equal(t, errWalletLocked, err)
+	next := locked.CreateWallet(KeyTypeP256)
+	assert.Nil(t, locked.Wallets[next].PrivateKey.D)
+
+	_, err = locked.Unlock("wrong")
+	assert.Equal(t, errWrongPassphrase, err)
+
+	_, err = locked.Unlock("secret")
+	assert.Nil(t, err)
+	assert.False(t, locked.IsLocked())
+	for address, wallet := range wallets.Wallets {
+		assert.Equal(t, wallet.PrivateKey.D, locked.Wallets[address].PrivateKey.D)
+	}
+	_, err = locked.Wallets[next].Signer()
+	assert.Nil(t, err)
+}
+
+func TestUnlockSessionExpiry(t *testing.T) {
+	chdirTemp(t)
+
+	saveUnlockSession([]byte("key"), time.Now().Add(time.Hour))
+	assert.Equal(t, []byte("key"), loadUnlockSession())
+
+	// The key of an expired session is removed as soon as it is read
+	saveUnlockSession([]byte("key"), time.Now().Add(-time.Second))
+	assert.Nil(t, loadUnlockSession())
+	_, err := os.Stat(walletUnlockFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWalletsDump(t *testing.T) {
+	wallets := newWallets()
+	imported := NewWallet(KeyTypeSecp256k1)
+	_, err := wallets.ImportKey(imported.PrivateKey)
+	assert.Nil(t, err)
+	wallets.CreateWallet(KeyTypeP256)
+	wallets.CreateWallet(KeyTypeSecp256k1)
+	wallets.AddMultisig(1, [][]byte{imported.PublicKey})
+
+	dump := bytes.Buffer{}
+	assert.Nil(t, wallets.Dump(&dump))
+
+	// A wallet without a seed takes the one of the dump
+	restored := newWallets()
+	addresses, err := restored.ImportDump(bytes.NewReader(dump.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(addresses))
+	assert.Equal(t, wallets.fileContent(), restored.fileContent())
+
+	// A wallet with its own seed imports the derived keys like the others
+	other := newWallets()
+	other.CreateWallet(KeyTypeP256)
+	_, err = other.ImportDump(bytes.NewReader(dump.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(other.Wallets))
+	assert.Equal(t, 3, len(other.fileContent().Wallets))
+	for address, wallet := range wallets.Wallets {
+		assert.Equal(t, wallet.PrivateKey.D, other.Wallets[address].PrivateKey.D)
+	}
+
+	_, err = other.ImportDump(bytes.NewReader([]byte("key 5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ 1abc\n")))
+	assert.NotNil(t, err)
+}
+
+func TestWalletsWatchOnly(t *testing.T) {
+	wallets := newWallets()
+	own := wallets.CreateWallet(KeyTypeP256)
+	cold := NewWallet(KeyTypeP256)
+	other := NewWallet(KeyTypeSecp256k1)
+
+	// A public key is watched at the address of its compressed form
+	address, err := wallets.AddWatchOnly("", encodeUncompressedPubKey(cold.PrivateKey.PublicKey))
+	assert.Nil(t, err)
+	assert.Equal(t, string(cold.GetAddress()), address)
+	_, err = wallets.AddWatchOnly(string(other.GetAddress()), nil)
+	assert.Nil(t, err)
+
+	_, err = wallets.AddWatchOnly(own, nil)
+	assert.NotNil(t, err)
+	_, err = wallets.AddWatchOnly("1abc", nil)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, map[string]bool{own: false, address: true, string(other.GetAddress()): true}, wallets.AllAddresses())
+
+	// Watch-only addresses can't be spent from, and signing never finds them
+	_, err = wallets.SpendingWallet(own)
+	assert.Nil(t, err)
+	_, err = wallets.SpendingWallet(address)
+	assert.NotNil(t, err)
+	assert.Nil(t, wallets.GetWalletByPubKeyHash(HashPubKey(cold.PublicKey)))
+
+	restored := newWallets()
+	assert.Nil(t, restored.load(wallets.fileContent()))
+	assert.Equal(t, wallets.Watched, restored.Watched)
+
+	dump := bytes.Buffer{}
+	assert.Nil(t, wallets.Dump(&dump))
+	imported := newWallets()
+	_, err = imported.ImportDump(&dump)
+	assert.Nil(t, err)
+	assert.Equal(t, wallets.Watched, imported.Watched)
+
+	// Importing the private key of a watched address makes it spendable
+	_, err = wallets.ImportKey(cold.PrivateKey)
+	assert.Nil(t, err)
+	assert.False(t, wallets.AllAddresses()[address])
+	assert.NotContains(t, wallets.Watched, address)
+	_, err = wallets.SpendingWallet(address)
+	assert.Nil(t, err)
+}
+
+func TestWalletsLabelsAndAccounts(t *testing.T) {
+	wallets := newWallets()
+	addresses := []string{}
+	for i := 0; i < 4; i++ {
+		addresses = append(addresses, wallets.CreateWallet(KeyTypeP256))
+	}
+
+	assert.Nil(t, wallets.SetLabel(addresses[0], "rent # march"))
+	assert.NotNil(t, wallets.SetLabel("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "unknown"))
+	assert.Nil(t, wallets.SetAccount(addresses[1], "savings"))
+	assert.Nil(t, wallets.SetAccount(addresses[2], "cold storage"))
+	assert.Nil(t, wallets.SetAccount(addresses[3], "savings"))
+	assert.Nil(t, wallets.SetAccount(addresses[3], defaultAccount))
+	assert.NotNil(t, wallets.SetAccount(addresses[3], " savings"))
+
+	// The default account comes first, then the others by name
+	groups := wallets.AccountGroups()
+	assert.Equal(t, 3, len(groups))
+	assert.Equal(t, []string{defaultAccount, "cold storage", "savings"}, []string{groups[0].Name, groups[1].Name, groups[2].Name})
+	assert.ElementsMatch(t, []string{addresses[0], addresses[3]}, groups[0].Addresses)
+	assert.True(t, sort.StringsAreSorted(groups[0].Addresses))
+
+	restored := newWallets()
+	assert.Nil(t, restored.load(wallets.fileContent()))
+	assert.Equal(t, wallets.Labels, restored.Labels)
+	assert.Equal(t, wallets.Accounts, restored.Accounts)
+
+	dump := bytes.Buffer{}
+	assert.Nil(t, wallets.Dump(&dump))
+	imported := newWallets()
+	_, err := imported.ImportDump(&dump)
+	assert.Nil(t, err)
+	assert.Equal(t, wallets.Labels, imported.Labels)
+	assert.Equal(t, wallets.Accounts, imported.Accounts)
+}
+
+func TestValidateAddressMalformed(t *testing.T) {
+	for _, address := range []string{"", "1", "abc", "0OIl"} {
+		assert.False(t, ValidateAddress(address), "Address '%s'", address)
+	}
+
+	assert.True(t, ValidateAddress(string(NewWallet(KeyTypeP256).GetAddress())))
+}
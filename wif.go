@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+)
+
+// Keys are exported in the Wallet Import Format: a version byte telling
+// the key type, the 32 byte private key and a flag for compressed public
+// keys, with a checksum. secp256k1 keys use Bitcoin's version byte
+
+const wifCompressed = byte(0x01)
+const wifLen = 1 + 32 + 1
+
+var wifVersions = map[KeyType]byte{
+	KeyTypeP256:      0x81,
+	KeyTypeSecp256k1: 0x80,
+}
+
+var errInvalidWIF = errors.New("Private key is not a valid WIF key")
+
+// EncodeWIF encodes a private key in the Wallet Import Format
+func EncodeWIF(privKey ecdsa.PrivateKey) string {
+	payload := []byte{wifVersions[curveKeyType(privKey.Curve)]}
+	payload = append(payload, bytes32(privKey.D)...)
+	payload = append(payload, wifCompressed)
+
+	return string(Base58Encode(append(payload, checksum(payload)...)))
+}
+
+// DecodeWIF decodes a private key encoded by EncodeWIF
+func DecodeWIF(wif string) (ecdsa.PrivateKey, error) {
+	data := Base58Decode([]byte(wif))
+	if len(data) != wifLen+addressChecksumLen {
+		return ecdsa.PrivateKey{}, errInvalidWIF
+	}
+
+	payload := data[:wifLen]
+	if !bytes.Equal(checksum(payload), data[wifLen:]) || payload[wifLen-1] != wifCompressed {
+		return ecdsa.PrivateKey{}, errInvalidWIF
+	}
+
+	for keyType, version := range wifVersions {
+		if payload[0] != version {
+			continue
+		}
+
+		d := new(big.Int).SetBytes(payload[1:33])
+		if d.Sign() == 0 || d.Cmp(keyType.Curve().Params().N) >= 0 {
+			return ecdsa.PrivateKey{}, errInvalidWIF
+		}
+
+		return newPrivateKey(keyType.Curve(), payload[1:33]), nil
+	}
+
+	return ecdsa.PrivateKey{}, errInvalidWIF
+}
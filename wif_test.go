@@ -0,0 +1,30 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWIF(t *testing.T) {
+	// Bitcoin's compressed WIF of a secp256k1 key
+	privKey := newPrivateKey(Secp256k1(), decodeHex("0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d"))
+	assert.Equal(t, "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617", EncodeWIF(privKey))
+
+	for keyType := range keyTypeNames {
+		privKey, _ := newKeyPair(keyType.Curve())
+		decoded, err := DecodeWIF(EncodeWIF(privKey))
+		assert.Nil(t, err)
+		assert.Equal(t, privKey.D, decoded.D)
+		assert.Equal(t, keyType, curveKeyType(decoded.Curve))
+	}
+
+	privKey, _ = newKeyPair(elliptic.P256())
+	wif := []byte(EncodeWIF(privKey))
+	wif[5]++
+	_, err := DecodeWIF(string(wif))
+	assert.Equal(t, errInvalidWIF, err)
+	_, err = DecodeWIF("1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2")
+	assert.Equal(t, errInvalidWIF, err)
+}